@@ -0,0 +1,48 @@
+package rapidjson
+
+// #include "rjwrapper.h"
+import "C"
+
+import "runtime"
+
+// Allocator picks how a Doc allocates its values
+type Allocator int
+
+const (
+	AllocatorCrt  Allocator = iota // malloc per value, memory is returned as values are removed
+	AllocatorPool                  // memory pool released all at once by Free, faster for parse then discard
+)
+
+type DocOptions struct {
+	Allocator     Allocator
+	InitialBuffer int  // bytes AllocatorPool allocates up front, later chunks are 64KB
+	CheckStale    bool // Containers return ErrStaleContainer once their value may be freed or moved
+}
+
+// MemoryStats reports the memory a Doc holds, values for AllocatorCrt and
+// whole chunks for AllocatorPool
+type MemoryStats struct {
+	Allocated int // bytes held now
+	Peak      int // most bytes held at once
+}
+
+// NewDocWithOptions creates a Doc with the given allocator
+func NewDocWithOptions(opts DocOptions) *Doc {
+	var json Doc
+	json.init(opts, true)
+	return &json
+}
+
+func (json *Doc) MemoryStats() MemoryStats {
+	defer runtime.KeepAlive(json)
+	var allocated, peak C.size_t
+	C.JsonMemoryStats(json.json, &allocated, &peak)
+	return MemoryStats{Allocated: int(allocated), Peak: int(peak)}
+}
+
+func (opts DocOptions) kind() C.int {
+	if opts.Allocator == AllocatorPool {
+		return C.JsonAllocatorPool
+	}
+	return C.JsonAllocatorCrt
+}
@@ -0,0 +1,63 @@
+package rapidjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func testAllocatorInput() string {
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = `{"name": "a string long enough to need its own allocation", "n": 1}`
+	}
+	return `{"items": [` + strings.Join(items, ",") + `]}`
+}
+
+func TestAllocators(t *testing.T) {
+	input := testAllocatorInput()
+	for _, opts := range []DocOptions{
+		{},
+		{Allocator: AllocatorPool},
+		{Allocator: AllocatorPool, InitialBuffer: 1 << 20},
+	} {
+		json := NewDocWithOptions(opts)
+		assert.Nil(t, json.ParseString(input), "should not error on parsing")
+		ct := json.GetContainer()
+		assert.Equal(t, 1000, len(ct.GetMemberOrNil("items").GetArrayOrNil()))
+		ct.GetMemberOrNil("items").GetArrayValue(999).AddValue("extra", "added after parsing")
+		str, _ := ct.GetPathContainerOrNil("items").GetArrayValue(999).GetMemberOrNil("extra").GetString()
+		assert.Equal(t, "added after parsing", str)
+
+		stats := json.MemoryStats()
+		assert.True(t, stats.Allocated > 1000*50, "should count the parsed strings")
+		assert.True(t, stats.Peak >= stats.Allocated)
+		json.Free()
+	}
+}
+
+func TestMemoryStats(t *testing.T) {
+	json := NewDoc()
+	defer json.Free()
+	assert.Equal(t, MemoryStats{}, json.MemoryStats())
+	assert.Nil(t, json.ParseString(testAllocatorInput()), "should not error on parsing")
+	parsed := json.MemoryStats()
+	assert.True(t, parsed.Allocated > 0)
+
+	// CRT memory is returned as values are removed, the peak stays
+	json.GetContainer().RemoveMember("items")
+	removed := json.MemoryStats()
+	assert.True(t, removed.Allocated < parsed.Allocated)
+	assert.Equal(t, parsed.Peak, removed.Peak)
+
+	pool := NewDocWithOptions(DocOptions{Allocator: AllocatorPool, InitialBuffer: 4096})
+	defer pool.Free()
+	assert.Equal(t, MemoryStats{Allocated: 4096, Peak: 4096}, pool.MemoryStats())
+	assert.Nil(t, pool.ParseString(testAllocatorInput()), "should not error on parsing")
+	// pool memory is held until Free
+	held := pool.MemoryStats()
+	assert.True(t, held.Allocated > 4096)
+	pool.GetContainer().RemoveMember("items")
+	assert.Equal(t, held, pool.MemoryStats())
+}
@@ -0,0 +1,260 @@
+package rapidjson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNotDuration = errors.New("Not a duration")
+	ErrOverflow    = errors.New("Number out of range")
+
+	coercions = []string{
+		"none",
+		"from string",
+		"from number",
+		"from bool",
+	}
+)
+
+// Coercion tells which conversion an As getter applied, so sloppy producers
+// can be logged
+type Coercion int
+
+const (
+	CoerceNone       Coercion = iota // the value already had the requested type
+	CoerceFromString                 // parsed from a string, "42" as 42
+	CoerceFromNumber                 // converted from a number, 1 as true or 2.0 as 2
+	CoerceFromBool                   // converted from a bool, true as 1
+)
+
+func (c Coercion) String() string {
+	if c < 0 || int(c) >= len(coercions) {
+		return fmt.Sprintf("Unknown coercion %d", int(c))
+	}
+	return coercions[c]
+}
+
+// the first float64 above the int range, 2^63 on 64-bit platforms
+const intLimit = -float64(math.MinInt)
+
+// AsInt is a lenient GetInt. Integers are taken as is, doubles with no
+// fraction are converted, true and false are 1 and 0, and a string holding a
+// JSON number follows the same rules. Fractions return ErrNotInt and values
+// beyond int return ErrOverflow, nothing is truncated
+func (ct *Container) AsInt() (int, Coercion, error) {
+	if ct == nil {
+		return 0, CoerceNone, ErrPathNotFound
+	} else if ct.stale() {
+		return 0, CoerceNone, ErrStaleContainer
+	}
+	switch ct.GetType() {
+	case TypeNumber:
+		switch ct.NumberKind() {
+		case NumberKindInt32, NumberKindUint32, NumberKindInt64:
+			n, _ := ct.GetInt64()
+			if n < math.MinInt || n > math.MaxInt {
+				return 0, CoerceNone, ErrOverflow
+			}
+			return int(n), CoerceNone, nil
+		case NumberKindUint64:
+			return 0, CoerceNone, ErrOverflow
+		default:
+			f, _ := ct.GetFloat()
+			n, err := intFromFloat(f)
+			return n, CoerceFromNumber, err
+		}
+	case TypeString:
+		str, _ := ct.GetString()
+		if !isNumber(str) {
+			return 0, CoerceFromString, ErrNotInt
+		}
+		n, err := strconv.ParseInt(str, 10, strconv.IntSize)
+		if err == nil {
+			return int(n), CoerceFromString, nil
+		} else if errors.Is(err, strconv.ErrRange) {
+			return 0, CoerceFromString, ErrOverflow
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return 0, CoerceFromString, ErrOverflow
+		}
+		n2, err := intFromFloat(f)
+		return n2, CoerceFromString, err
+	case TypeTrue:
+		return 1, CoerceFromBool, nil
+	case TypeFalse:
+		return 0, CoerceFromBool, nil
+	default:
+		return 0, CoerceNone, ErrNotInt
+	}
+}
+func intFromFloat(f float64) (int, error) {
+	if f != math.Trunc(f) {
+		return 0, ErrNotInt
+	} else if f < -intLimit || f >= intLimit {
+		return 0, ErrOverflow
+	}
+	return int(f), nil
+}
+
+// AsFloat is a lenient GetFloat. Every number is taken as is, integers
+// included, true and false are 1 and 0, and a string holding a JSON number
+// is parsed. Strings beyond float64 return ErrOverflow
+func (ct *Container) AsFloat() (float64, Coercion, error) {
+	if ct == nil {
+		return 0, CoerceNone, ErrPathNotFound
+	} else if ct.stale() {
+		return 0, CoerceNone, ErrStaleContainer
+	}
+	switch ct.GetType() {
+	case TypeNumber:
+		switch ct.NumberKind() {
+		case NumberKindInt32, NumberKindUint32, NumberKindInt64:
+			n, _ := ct.GetInt64()
+			return float64(n), CoerceNone, nil
+		case NumberKindUint64:
+			n, _ := ct.GetUint64()
+			return float64(n), CoerceNone, nil
+		default:
+			f, _ := ct.GetFloat()
+			return f, CoerceNone, nil
+		}
+	case TypeString:
+		str, _ := ct.GetString()
+		if !isNumber(str) {
+			return 0, CoerceFromString, ErrNotFloat
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return 0, CoerceFromString, ErrOverflow
+		}
+		return f, CoerceFromString, nil
+	case TypeTrue:
+		return 1, CoerceFromBool, nil
+	case TypeFalse:
+		return 0, CoerceFromBool, nil
+	default:
+		return 0, CoerceNone, ErrNotFloat
+	}
+}
+
+// AsBool is a lenient GetBool. The numbers 1 and 0 are true and false, any
+// other number returns ErrNotBool, and strings are parsed by
+// strconv.ParseBool: 1, t, T, TRUE, true, True, 0, f, F, FALSE, false, False
+func (ct *Container) AsBool() (bool, Coercion, error) {
+	if ct == nil {
+		return false, CoerceNone, ErrPathNotFound
+	} else if ct.stale() {
+		return false, CoerceNone, ErrStaleContainer
+	}
+	switch ct.GetType() {
+	case TypeTrue:
+		return true, CoerceNone, nil
+	case TypeFalse:
+		return false, CoerceNone, nil
+	case TypeNumber:
+		switch f, _, _ := ct.AsFloat(); f {
+		case 1:
+			return true, CoerceFromNumber, nil
+		case 0:
+			return false, CoerceFromNumber, nil
+		}
+		return false, CoerceFromNumber, ErrNotBool
+	case TypeString:
+		str, _ := ct.GetString()
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return false, CoerceFromString, ErrNotBool
+		}
+		return b, CoerceFromString, nil
+	default:
+		return false, CoerceNone, ErrNotBool
+	}
+}
+
+// AsString is a lenient GetString. Numbers give their text, as
+// GetNumberString, and bools give true or false. Null, arrays and objects
+// return ErrNotString
+func (ct *Container) AsString() (string, Coercion, error) {
+	if ct == nil {
+		return "", CoerceNone, ErrPathNotFound
+	} else if ct.stale() {
+		return "", CoerceNone, ErrStaleContainer
+	}
+	switch ct.GetType() {
+	case TypeString:
+		str, err := ct.GetString()
+		return str, CoerceNone, err
+	case TypeNumber:
+		str, err := ct.GetNumberString()
+		return str, CoerceFromNumber, err
+	case TypeTrue:
+		return "true", CoerceFromBool, nil
+	case TypeFalse:
+		return "false", CoerceFromBool, nil
+	default:
+		return "", CoerceNone, ErrNotString
+	}
+}
+
+// AsDuration reads a duration. A number counts units, so 1.5 with
+// time.Second is 1.5s, and is rounded to the nearest nanosecond. A string is
+// parsed by time.ParseDuration, such as "300ms" or "1h30m", or failing that
+// as a JSON number of units. Durations beyond time.Duration return
+// ErrOverflow, NaN returns ErrNotDuration
+func (ct *Container) AsDuration(unit time.Duration) (time.Duration, Coercion, error) {
+	if ct == nil {
+		return 0, CoerceNone, ErrPathNotFound
+	} else if ct.stale() {
+		return 0, CoerceNone, ErrStaleContainer
+	}
+	switch ct.GetType() {
+	case TypeNumber:
+		if n, err := ct.GetInt64(); err == nil {
+			d, err := intDuration(n, unit)
+			return d, CoerceNone, err
+		}
+		f, _, _ := ct.AsFloat()
+		d, err := floatDuration(f, unit)
+		return d, CoerceNone, err
+	case TypeString:
+		str, _ := ct.GetString()
+		if d, err := time.ParseDuration(str); err == nil {
+			return d, CoerceFromString, nil
+		} else if !isNumber(str) {
+			return 0, CoerceFromString, fmt.Errorf("%w: %v", ErrNotDuration, err)
+		}
+		if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+			d, err := intDuration(n, unit)
+			return d, CoerceFromString, err
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return 0, CoerceFromString, ErrOverflow
+		}
+		d, err := floatDuration(f, unit)
+		return d, CoerceFromString, err
+	default:
+		return 0, CoerceNone, ErrNotDuration
+	}
+}
+func intDuration(n int64, unit time.Duration) (time.Duration, error) {
+	d := time.Duration(n) * unit
+	if unit != 0 && d/unit != time.Duration(n) {
+		return 0, ErrOverflow
+	}
+	return d, nil
+}
+func floatDuration(f float64, unit time.Duration) (time.Duration, error) {
+	ns := math.Round(f * float64(unit))
+	if math.IsNaN(ns) {
+		return 0, ErrNotDuration
+	} else if ns < math.MinInt64 || ns >= -math.MinInt64 {
+		return 0, ErrOverflow
+	}
+	return time.Duration(ns), nil
+}
@@ -0,0 +1,163 @@
+package rapidjson
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+const testCoerce = `{"int": 42, "whole": 2.0, "frac": 2.5, "big": 18446744073709551615, "huge": 1e300,
+	"str": "42", "strWhole": "4.2e1", "strFrac": "1.5", "strBig": "9223372036854775808", "strInf": "1e999", "word": "abc",
+	"true": true, "false": false, "one": 1, "zero": 0, "two": 2, "strBool": "TRUE",
+	"null": null, "arr": [1], "obj": {}, "dur": "1h30m", "strSecs": "1.5"}`
+
+func TestAsInt(t *testing.T) {
+	json, err := NewParsedStringJson(testCoerce)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	check := func(key string, expected int, how Coercion) {
+		n, c, err := ct.GetMemberOrNil(key).AsInt()
+		assert.Nil(t, err, "should not error on "+key)
+		assert.Equal(t, expected, n, key)
+		assert.Equal(t, how, c, key)
+	}
+	check("int", 42, CoerceNone)
+	check("whole", 2, CoerceFromNumber)
+	check("str", 42, CoerceFromString)
+	check("strWhole", 42, CoerceFromString)
+	check("true", 1, CoerceFromBool)
+	check("false", 0, CoerceFromBool)
+
+	fails := map[string]error{
+		"frac": ErrNotInt, "big": ErrOverflow, "huge": ErrOverflow, "strFrac": ErrNotInt,
+		"strBig": ErrOverflow, "strInf": ErrOverflow, "word": ErrNotInt,
+		"null": ErrNotInt, "arr": ErrNotInt, "obj": ErrNotInt, "missing": ErrPathNotFound,
+	}
+	for key, expected := range fails {
+		_, _, err := ct.GetMemberOrNil(key).AsInt()
+		assert.Equal(t, expected, err, key)
+	}
+}
+
+func TestAsFloat(t *testing.T) {
+	json, err := NewParsedStringJson(testCoerce)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	f, how, err := ct.GetMemberOrNil("int").AsFloat()
+	assert.Nil(t, err, "should not error on int")
+	assert.Equal(t, 42.0, f)
+	assert.Equal(t, CoerceNone, how)
+	f, how, err = ct.GetMemberOrNil("big").AsFloat()
+	assert.Nil(t, err, "should not error on uint64")
+	assert.Equal(t, 18446744073709551615.0, f)
+	assert.Equal(t, CoerceNone, how)
+	f, how, err = ct.GetMemberOrNil("strFrac").AsFloat()
+	assert.Nil(t, err, "should not error on string")
+	assert.Equal(t, 1.5, f)
+	assert.Equal(t, CoerceFromString, how)
+	f, how, err = ct.GetMemberOrNil("true").AsFloat()
+	assert.Nil(t, err, "should not error on bool")
+	assert.Equal(t, 1.0, f)
+	assert.Equal(t, CoerceFromBool, how)
+
+	_, _, err = ct.GetMemberOrNil("strInf").AsFloat()
+	assert.Equal(t, ErrOverflow, err)
+	_, _, err = ct.GetMemberOrNil("word").AsFloat()
+	assert.Equal(t, ErrNotFloat, err)
+	_, _, err = ct.GetMemberOrNil("null").AsFloat()
+	assert.Equal(t, ErrNotFloat, err)
+}
+
+func TestAsBoolAndString(t *testing.T) {
+	json, err := NewParsedStringJson(testCoerce)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	b, how, err := ct.GetMemberOrNil("false").AsBool()
+	assert.Nil(t, err, "should not error on bool")
+	assert.False(t, b)
+	assert.Equal(t, CoerceNone, how)
+	b, how, err = ct.GetMemberOrNil("one").AsBool()
+	assert.Nil(t, err, "should not error on 1")
+	assert.True(t, b)
+	assert.Equal(t, CoerceFromNumber, how)
+	b, how, err = ct.GetMemberOrNil("zero").AsBool()
+	assert.Nil(t, err, "should not error on 0")
+	assert.False(t, b)
+	assert.Equal(t, CoerceFromNumber, how)
+	b, how, err = ct.GetMemberOrNil("strBool").AsBool()
+	assert.Nil(t, err, "should not error on string")
+	assert.True(t, b)
+	assert.Equal(t, CoerceFromString, how)
+	_, _, err = ct.GetMemberOrNil("two").AsBool()
+	assert.Equal(t, ErrNotBool, err)
+	_, _, err = ct.GetMemberOrNil("word").AsBool()
+	assert.Equal(t, ErrNotBool, err)
+
+	s, how, err := ct.GetMemberOrNil("word").AsString()
+	assert.Nil(t, err, "should not error on string")
+	assert.Equal(t, "abc", s)
+	assert.Equal(t, CoerceNone, how)
+	s, how, err = ct.GetMemberOrNil("int").AsString()
+	assert.Nil(t, err, "should not error on number")
+	assert.Equal(t, "42", s)
+	assert.Equal(t, CoerceFromNumber, how)
+	s, how, err = ct.GetMemberOrNil("true").AsString()
+	assert.Nil(t, err, "should not error on bool")
+	assert.Equal(t, "true", s)
+	assert.Equal(t, CoerceFromBool, how)
+	_, _, err = ct.GetMemberOrNil("obj").AsString()
+	assert.Equal(t, ErrNotString, err)
+
+	assert.Equal(t, "from string", CoerceFromString.String())
+}
+
+func TestAsDuration(t *testing.T) {
+	json, err := NewParsedStringJson(testCoerce)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	d, how, err := ct.GetMemberOrNil("int").AsDuration(time.Millisecond)
+	assert.Nil(t, err, "should not error on int")
+	assert.Equal(t, 42*time.Millisecond, d)
+	assert.Equal(t, CoerceNone, how)
+	d, how, err = ct.GetMemberOrNil("frac").AsDuration(time.Second)
+	assert.Nil(t, err, "should not error on double")
+	assert.Equal(t, 2500*time.Millisecond, d)
+	assert.Equal(t, CoerceNone, how)
+	d, how, err = ct.GetMemberOrNil("dur").AsDuration(time.Second)
+	assert.Nil(t, err, "should not error on duration string")
+	assert.Equal(t, 90*time.Minute, d)
+	assert.Equal(t, CoerceFromString, how)
+	d, how, err = ct.GetMemberOrNil("strSecs").AsDuration(time.Second)
+	assert.Nil(t, err, "should not error on number string")
+	assert.Equal(t, 1500*time.Millisecond, d)
+	assert.Equal(t, CoerceFromString, how)
+
+	_, _, err = ct.GetMemberOrNil("big").AsDuration(time.Second)
+	assert.Equal(t, ErrOverflow, err)
+	_, _, err = ct.GetMemberOrNil("strBig").AsDuration(time.Second)
+	assert.Equal(t, ErrOverflow, err)
+	_, _, err = ct.GetMemberOrNil("word").AsDuration(time.Second)
+	assert.True(t, errors.Is(err, ErrNotDuration))
+	_, _, err = ct.GetMemberOrNil("true").AsDuration(time.Second)
+	assert.Equal(t, ErrNotDuration, err)
+
+	nan, err := NewParsedStringJsonWithOptions(`[NaN, Infinity]`, ParseOptions{NanAndInf: true})
+	assert.Nil(t, err, "should not error on parsing NaN")
+	defer nan.Free()
+	_, _, err = nan.GetContainer().GetArrayValue(0).AsDuration(time.Second)
+	assert.Equal(t, ErrNotDuration, err)
+	_, _, err = nan.GetContainer().GetArrayValue(1).AsDuration(time.Second)
+	assert.Equal(t, ErrOverflow, err)
+	_, _, err = nan.GetContainer().GetArrayValue(1).AsDuration(0)
+	assert.Equal(t, ErrNotDuration, err)
+}
@@ -0,0 +1,368 @@
+package rapidjson
+
+// #include <stdlib.h>
+// #include "rjwrapper.h"
+import "C"
+import "unsafe"
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+)
+
+var numberType = reflect.TypeOf(json.Number(""))
+
+// NumberMode picks the Go type numbers convert to
+type NumberMode int
+
+const (
+	NumberInt64      NumberMode = iota // int64 for integers, uint64 above MaxInt64, float64 otherwise
+	NumberFloat64                      // float64 for every number
+	NumberJSONNumber                   // json.Number holding the number's text
+)
+
+type ConvertOptions struct {
+	Numbers NumberMode
+}
+
+// ToInterface converts the whole tree to native Go values: nil, bool,
+// numbers, string, []interface{} and map[string]interface{}
+func (ct *Container) ToInterface() (interface{}, error) {
+	return ct.ToInterfaceWithOptions(ConvertOptions{})
+}
+func (ct *Container) ToInterfaceWithOptions(opts ConvertOptions) (interface{}, error) {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return nil, ErrPathNotFound
+	} else if ct.stale() {
+		return nil, ErrStaleContainer
+	}
+	var size C.size_t
+	buffer := C.ValEncode(ct.ct, &size)
+	defer C.free(unsafe.Pointer(buffer))
+
+	d := decoder{buf: unsafe.Slice((*byte)(unsafe.Pointer(buffer)), int(size)), opts: opts}
+	return d.value(), nil
+}
+func (json *Doc) ToInterface() (interface{}, error) {
+	return json.GetContainer().ToInterface()
+}
+func (json *Doc) ToInterfaceWithOptions(opts ConvertOptions) (interface{}, error) {
+	return json.GetContainer().ToInterfaceWithOptions(opts)
+}
+
+// decoder reads the ValEncode format, buf is a view of C memory so
+// everything kept is copied out
+type decoder struct {
+	buf    []byte
+	opts   ConvertOptions
+	err    error    // first type mismatch when decoding into Go types
+	fields []string // struct fields leading to the current value
+}
+
+func (d *decoder) uint32() int {
+	n := binary.NativeEndian.Uint32(d.buf)
+	d.buf = d.buf[4:]
+	return int(n)
+}
+func (d *decoder) uint64() uint64 {
+	n := binary.NativeEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return n
+}
+func (d *decoder) string() string {
+	size := d.uint32()
+	str := string(d.buf[:size])
+	d.buf = d.buf[size:]
+	return str
+}
+func (d *decoder) skip() {
+	tag := d.buf[0]
+	d.buf = d.buf[1:]
+	switch tag {
+	case C.JsonTagInt64, C.JsonTagUint64, C.JsonTagDouble:
+		d.buf = d.buf[8:]
+	case C.JsonTagString, C.JsonTagRawNumber:
+		d.buf = d.buf[d.uint32():]
+	case C.JsonTagArray:
+		for count := d.uint32(); count > 0; count-- {
+			d.skip()
+		}
+	case C.JsonTagObject:
+		for count := d.uint32(); count > 0; count-- {
+			d.buf = d.buf[d.uint32():]
+			d.skip()
+		}
+	}
+}
+func (d *decoder) value() interface{} {
+	tag := d.buf[0]
+	d.buf = d.buf[1:]
+	switch tag {
+	case C.JsonTagFalse:
+		return false
+	case C.JsonTagTrue:
+		return true
+	case C.JsonTagInt64, C.JsonTagUint64, C.JsonTagDouble:
+		bits := d.uint64()
+		if d.opts.Numbers == NumberJSONNumber {
+			return json.Number(numberText(tag, bits))
+		}
+		return d.number(tag, bits)
+	case C.JsonTagRawNumber:
+		text := d.string()
+		if d.opts.Numbers == NumberJSONNumber {
+			return json.Number(text)
+		}
+		return d.number(rawNumberBits(text))
+	case C.JsonTagString:
+		return d.string()
+	case C.JsonTagArray:
+		count := d.uint32()
+		result := make([]interface{}, count)
+		for i := range result {
+			result[i] = d.value()
+		}
+		return result
+	case C.JsonTagObject:
+		count := d.uint32()
+		result := make(map[string]interface{}, count)
+		for i := 0; i < count; i++ {
+			key := d.string()
+			result[key] = d.value()
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+func (d *decoder) number(tag byte, bits uint64) interface{} {
+	if d.opts.Numbers == NumberFloat64 {
+		return numberFloat(tag, bits)
+	}
+	switch tag {
+	case C.JsonTagInt64:
+		return int64(bits)
+	case C.JsonTagUint64:
+		return bits
+	}
+	return math.Float64frombits(bits)
+}
+
+// values nested deeper than this are checked for cycles, as encoding/json
+const cycleCheckDepth = 1000
+
+// setState follows the maps, slices and pointers being set, a value reached
+// again from within itself is a cycle
+type setState struct {
+	depth int
+	seen  map[interface{}]struct{}
+}
+
+// enter returns an *json.UnsupportedValueError when rv is a cycle, leave
+// must follow otherwise
+func (st *setState) enter(rv reflect.Value) error {
+	st.depth++
+	if st.depth <= cycleCheckDepth {
+		return nil
+	}
+	if st.seen == nil {
+		st.seen = map[interface{}]struct{}{}
+	}
+	key := cycleKey(rv)
+	if _, ok := st.seen[key]; ok {
+		st.depth--
+		return &json.UnsupportedValueError{Value: rv, Str: fmt.Sprintf("encountered a cycle via %s", rv.Type())}
+	}
+	st.seen[key] = struct{}{}
+	return nil
+}
+func (st *setState) leave(rv reflect.Value) {
+	if st.depth > cycleCheckDepth {
+		delete(st.seen, cycleKey(rv))
+	}
+	st.depth--
+}
+
+// cycleKey tells values apart by address, and slices also by length since
+// a slice of a slice shares its address
+func cycleKey(rv reflect.Value) interface{} {
+	if rv.Kind() == reflect.Slice {
+		return struct {
+			ptr unsafe.Pointer
+			len int
+		}{rv.UnsafePointer(), rv.Len()}
+	}
+	return rv.UnsafePointer()
+}
+
+// setReflect builds nested values in place, maps and slices follow
+// encoding/json: nil is null, []byte is a base64 string, map keys are
+// sorted and json.Marshaler and encoding.TextMarshaler are honored. Cycles
+// return an *json.UnsupportedValueError
+func (ct *Container) setReflect(rv reflect.Value, st *setState) error {
+	if !rv.IsValid() {
+		C.SetNull(ct.ct)
+		return nil
+	}
+	if ok, err := ct.setMarshaler(rv); ok {
+		return err
+	}
+	if rv.Type() == numberType {
+		return ct.setNumber(json.Number(rv.String()))
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		C.SetBool(ct.ct, BoolToC(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		C.SetInt64(ct.ct, C.int64_t(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		C.SetUint64(ct.ct, C.uint64_t(rv.Uint()))
+	case reflect.Float32:
+		// keep the shortest float32 text, 0.1 rather than 0.10000000149011612
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(rv.Float(), 'g', -1, 32), 64)
+		C.SetDouble(ct.ct, C.double(f))
+	case reflect.Float64:
+		C.SetDouble(ct.ct, C.double(rv.Float()))
+	case reflect.String:
+		cStr, size := stringToC(rv.String())
+		C.SetString(ct.doc.json, ct.ct, cStr, size)
+	case reflect.Interface:
+		if rv.IsNil() {
+			C.SetNull(ct.ct)
+			return nil
+		}
+		return ct.setReflect(rv.Elem(), st)
+	case reflect.Pointer:
+		if rv.IsNil() {
+			C.SetNull(ct.ct)
+			return nil
+		}
+		if err := st.enter(rv); err != nil {
+			return err
+		}
+		defer st.leave(rv)
+		return ct.setReflect(rv.Elem(), st)
+	case reflect.Slice:
+		if rv.IsNil() {
+			C.SetNull(ct.ct)
+			return nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			cStr, size := stringToC(base64.StdEncoding.EncodeToString(rv.Bytes()))
+			C.SetString(ct.doc.json, ct.ct, cStr, size)
+			return nil
+		}
+		if err := st.enter(rv); err != nil {
+			return err
+		}
+		defer st.leave(rv)
+		return ct.setArray(rv, st)
+	case reflect.Array:
+		return ct.setArray(rv, st)
+	case reflect.Map:
+		if rv.IsNil() {
+			C.SetNull(ct.ct)
+			return nil
+		}
+		if err := st.enter(rv); err != nil {
+			return err
+		}
+		defer st.leave(rv)
+		return ct.setMap(rv, st)
+	case reflect.Struct:
+		return ct.setStruct(rv, st)
+	default:
+		return ErrBadType
+	}
+	return nil
+}
+func (ct *Container) setArray(rv reflect.Value, st *setState) error {
+	C.InitArray(ct.ct)
+	for i := 0; i < rv.Len(); i++ {
+		item := ct.derive(C.ArrayAppendNull(ct.doc.json, ct.ct))
+		err := item.setReflect(rv.Index(i), st)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (ct *Container) setMap(rv reflect.Value, st *setState) error {
+	type member struct {
+		key   string
+		value reflect.Value
+	}
+	members := make([]member, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		var key string
+		switch {
+		case k.Kind() == reflect.String:
+			key = k.String()
+		case k.Type().Implements(textMarshalerType) && k.CanInterface():
+			if k.Kind() == reflect.Pointer && k.IsNil() {
+				break
+			}
+			b, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return &json.MarshalerError{Type: k.Type(), Err: err}
+			}
+			key = string(b)
+		case k.CanInt():
+			key = strconv.FormatInt(k.Int(), 10)
+		case k.CanUint():
+			key = strconv.FormatUint(k.Uint(), 10)
+		default:
+			return ErrBadType
+		}
+		members = append(members, member{key: key, value: iter.Value()})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].key < members[j].key })
+
+	ct.ct = C.InitObj(ct.ct)
+	for _, m := range members {
+		cStr, size := stringToC(m.key)
+		item := ct.derive(C.AddStrMemberNull(ct.doc.json, ct.ct, cStr, size))
+		err := item.setReflect(m.value, st)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setNumber keeps integers exact when the text allows it
+func (ct *Container) setNumber(n json.Number) error {
+	str := string(n)
+	if !isNumber(str) {
+		return fmt.Errorf("%w: invalid json.Number %q", ErrBadType, str)
+	}
+	if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+		C.SetInt64(ct.ct, C.int64_t(i))
+	} else if u, err := strconv.ParseUint(str, 10, 64); err == nil {
+		C.SetUint64(ct.ct, C.uint64_t(u))
+	} else if f, err := strconv.ParseFloat(str, 64); err == nil && !math.IsInf(f, 0) {
+		C.SetDouble(ct.ct, C.double(f))
+	} else {
+		return fmt.Errorf("%w: json.Number %q out of range", ErrBadType, str)
+	}
+	return nil
+}
+
+// isNumber checks str against the JSON number grammar
+func isNumber(str string) bool {
+	return len(str) > 0 && (str[0] == '-' || str[0] >= '0' && str[0] <= '9') && json.Valid([]byte(str)) &&
+		str[len(str)-1] >= '0' && str[len(str)-1] <= '9'
+}
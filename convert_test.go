@@ -0,0 +1,114 @@
+package rapidjson
+
+import (
+	encjson "encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func TestToInterface(t *testing.T) {
+	json, err := NewParsedStringJson(testJSON1)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	v, err := json.ToInterface()
+	assert.Nil(t, err, "should not error on conversion")
+	expected := map[string]interface{}{
+		"member1": int64(12345),
+		"member2": []interface{}{int64(1), int64(2), int64(3), int64(4), int64(5)},
+		"member3": map[string]interface{}{"sub1": 1.234, "sub2": true, "sub3": nil},
+		"member4": "rapidjson is awesome!",
+	}
+	assert.Equal(t, expected, v)
+
+	v, err = json.GetContainer().GetMemberOrNil("member2").ToInterface()
+	assert.Nil(t, err, "should not error on sub container")
+	assert.Equal(t, expected["member2"], v)
+
+	v, err = json.GetContainer().GetMemberOrNil("member4").ToInterface()
+	assert.Nil(t, err, "should not error on scalar")
+	assert.Equal(t, "rapidjson is awesome!", v)
+
+	_, err = json.GetContainer().GetMemberOrNil("missing").ToInterface()
+	assert.Equal(t, ErrPathNotFound, err)
+}
+
+func TestToInterfaceNumbers(t *testing.T) {
+	json, err := NewParsedStringJson(`[-1, 18446744073709551615, 2.5, 1e300, {"a\u0000b": [""]}]`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	v, err := json.ToInterface()
+	assert.Nil(t, err, "should not error on int64 mode")
+	assert.Equal(t, []interface{}{int64(-1), uint64(18446744073709551615), 2.5, 1e300,
+		map[string]interface{}{"a\x00b": []interface{}{""}}}, v)
+
+	v, err = json.ToInterfaceWithOptions(ConvertOptions{Numbers: NumberFloat64})
+	assert.Nil(t, err, "should not error on float64 mode")
+	assert.Equal(t, []interface{}{-1.0, 18446744073709551615.0, 2.5, 1e300,
+		map[string]interface{}{"a\x00b": []interface{}{""}}}, v)
+
+	v, err = json.ToInterfaceWithOptions(ConvertOptions{Numbers: NumberJSONNumber})
+	assert.Nil(t, err, "should not error on json.Number mode")
+	assert.Equal(t, []interface{}{encjson.Number("-1"), encjson.Number("18446744073709551615"), encjson.Number("2.5"), encjson.Number("1e+300"),
+		map[string]interface{}{"a\x00b": []interface{}{""}}}, v)
+
+	// matches encoding/json for a large document
+	large := "[" + strings.Repeat(`{"a":[1,2.5,"x",null,true]},`, 1000) + "{}]"
+	json2, err := NewParsedStringJson(large)
+	assert.Nil(t, err, "should not error on parsing large input")
+	defer json2.Free()
+	v, err = json2.ToInterfaceWithOptions(ConvertOptions{Numbers: NumberFloat64})
+	assert.Nil(t, err, "should not error on large conversion")
+	var std interface{}
+	assert.Nil(t, encjson.Unmarshal([]byte(large), &std))
+	assert.Equal(t, std, v)
+}
+
+type testID uint16
+
+func TestSetValueReflect(t *testing.T) {
+	json := NewDoc()
+	defer json.Free()
+	ct := json.GetContainerNewObj()
+
+	name := "pointer"
+	var nilMap map[string]int
+	assert.Nil(t, ct.AddValue("uints", []interface{}{uint(1), uint8(2), uint16(3), uint32(4), uint64(18446744073709551615), testID(6)}))
+	assert.Nil(t, ct.AddValue("floats", []float32{0.1, 2.5}))
+	assert.Nil(t, ct.AddValue("strings", []string{"a", "b"}))
+	assert.Nil(t, ct.AddValue("array", [2]bool{true, false}))
+	assert.Nil(t, ct.AddValue("map", map[string]interface{}{"b": []int{1}, "a": map[int]string{2: "two", 1: "one"}, "c": nil}))
+	assert.Nil(t, ct.AddValue("pointer", &name))
+	assert.Nil(t, ct.AddValue("nil", nilMap))
+	assert.Nil(t, ct.AddValue("bytes", []byte("hi")))
+	assert.Nil(t, ct.AddValue("numbers", []encjson.Number{"12", "18446744073709551615", "-1.5e3"}))
+	assert.Equal(t, `{"uints":[1,2,3,4,18446744073709551615,6],"floats":[0.1,2.5],"strings":["a","b"],"array":[true,false],`+
+		`"map":{"a":{"1":"one","2":"two"},"b":[1],"c":null},"pointer":"pointer","nil":null,"bytes":"aGk=","numbers":[12,18446744073709551615,-1500.0]}`, json.String())
+
+	arr := json.NewContainerArray()
+	assert.Nil(t, arr.ArrayAppend(map[string]float32{"x": 1.5}))
+	assert.Nil(t, arr.ArrayAppend([]interface{}{[]interface{}{}, map[string]interface{}{}}))
+	assert.Equal(t, `[{"x":1.5},[[],{}]]`, arr.String())
+
+	// same result as building from ToInterface output
+	v, err := ct.ToInterface()
+	assert.Nil(t, err, "should not error on conversion")
+	copied := json.NewContainer()
+	assert.Nil(t, copied.SetValue(v))
+	assert.True(t, copied.IsEqual(ct))
+
+	assert.Equal(t, ErrBadType, ct.AddValue("func", func() {}))
+	assert.Equal(t, ErrBadType, ct.AddValue("chans", []chan int{make(chan int)}))
+	assert.Equal(t, ErrBadType, arr.ArrayAppend(map[bool]int{true: 1}))
+	assert.True(t, errors.Is(ct.SetMemberValue("uints", encjson.Number("0x10")), ErrBadType), "should error on bad json.Number")
+	assert.True(t, errors.Is(ct.SetMemberValue("uints", encjson.Number("1e999")), ErrBadType), "should error on out of range json.Number")
+
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = []interface{}{cyclic}
+	var unsupported *encjson.UnsupportedValueError
+	assert.True(t, errors.As(ct.SetMemberValue("cyclic", cyclic), &unsupported), "should error on a cycle")
+}
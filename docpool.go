@@ -0,0 +1,62 @@
+package rapidjson
+
+import "sync"
+
+// DocPool reuses Docs across parses, like sync.Pool. The zero value is
+// ready to use. Idle Docs hold C++ memory the GC can't see, so unlike
+// sync.Pool they're kept until Close rather than dropped on a GC
+type DocPool struct {
+	Options   DocOptions // allocator of new Docs
+	MaxMemory int        // Docs holding more bytes after Reset are freed by Put, 0 for no cap
+	MaxIdle   int        // idle Docs kept, 0 for no cap
+
+	mu   sync.Mutex
+	idle []*Doc
+}
+
+// Get returns an empty Doc, reused or new
+func (pool *DocPool) Get() *Doc {
+	pool.mu.Lock()
+	if n := len(pool.idle); n > 0 {
+		json := pool.idle[n-1]
+		pool.idle[n-1] = nil
+		pool.idle = pool.idle[:n-1]
+		pool.mu.Unlock()
+		return json
+	}
+	pool.mu.Unlock()
+	return NewDocWithOptions(pool.Options)
+}
+
+// Put resets the Doc and keeps it for Get, or frees it when it holds more
+// than MaxMemory or MaxIdle Docs are kept already. The Doc and its
+// Containers must not be used afterwards
+func (pool *DocPool) Put(json *Doc) {
+	if json == nil || json.json == nil {
+		return
+	}
+	json.Reset()
+	if pool.MaxMemory > 0 && json.MemoryStats().Allocated > pool.MaxMemory {
+		json.Free()
+		return
+	}
+	pool.mu.Lock()
+	if pool.MaxIdle > 0 && len(pool.idle) >= pool.MaxIdle {
+		pool.mu.Unlock()
+		json.Free()
+		return
+	}
+	pool.idle = append(pool.idle, json)
+	pool.mu.Unlock()
+}
+
+// Close frees the idle Docs, the pool can still be used afterwards
+func (pool *DocPool) Close() {
+	pool.mu.Lock()
+	idle := pool.idle
+	pool.idle = nil
+	pool.mu.Unlock()
+	for _, json := range idle {
+		json.Free()
+	}
+}
@@ -0,0 +1,67 @@
+package rapidjson
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func TestDocPool(t *testing.T) {
+	SetLeakTracking(true)
+	defer SetLeakTracking(false)
+
+	pool := DocPool{Options: DocOptions{Allocator: AllocatorPool, InitialBuffer: 4096}, MaxIdle: 2}
+	json := pool.Get()
+	assert.Nil(t, json.ParseString(`{"a": 1}`), "should not error on parsing")
+	pool.Put(json)
+	again := pool.Get()
+	assert.True(t, json == again, "should reuse the Doc")
+	assert.Equal(t, "null", again.String())
+
+	// beyond MaxIdle Docs are freed
+	docs := []*Doc{again, pool.Get(), pool.Get()}
+	for _, json := range docs {
+		pool.Put(json)
+	}
+	assert.Equal(t, 2, len(LiveDocs()))
+	pool.Close()
+	assert.Nil(t, CheckLeaks())
+}
+
+func TestDocPoolMaxMemory(t *testing.T) {
+	pool := DocPool{Options: DocOptions{Allocator: AllocatorPool}, MaxMemory: 128 * 1024}
+	defer pool.Close()
+
+	small := pool.Get()
+	assert.Nil(t, small.ParseString(`{"a": 1}`), "should not error on parsing")
+	pool.Put(small)
+	assert.True(t, pool.Get() == small, "should keep a small Doc")
+
+	big := pool.Get()
+	assert.Nil(t, big.ParseString(`[`+strings.Repeat(`"a string long enough to need its own allocation",`, 5000)+`0]`), "should not error on parsing")
+	pool.Put(big)
+	assert.Nil(t, big.json, "should free a Doc above MaxMemory")
+	pool.Put(small)
+}
+
+func TestDocPoolConcurrent(t *testing.T) {
+	var pool DocPool
+	defer pool.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				json := pool.Get()
+				assert.Nil(t, json.ParseString(`{"n": [1, 2, 3]}`), "should not error on parsing")
+				n, _ := json.GetContainer().GetMemberOrNil("n").GetArraySize()
+				assert.Equal(t, 3, n)
+				pool.Put(json)
+			}
+		}()
+	}
+	wg.Wait()
+}
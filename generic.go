@@ -0,0 +1,166 @@
+package rapidjson
+
+// #include <stdlib.h>
+// #include "rjwrapper.h"
+import "C"
+import "unsafe"
+
+import (
+	"reflect"
+	"runtime"
+)
+
+// Scalar is every type the generic getters convert to, named types included
+type Scalar interface {
+	~bool | ~string | ~float32 | ~float64 |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// Get reads the scalar at a dotted path below ct, an empty path reads ct
+// itself. Errors follow the typed getters: ErrNotInt, ErrNotUint, ErrNotFloat,
+// ErrNotBool or ErrNotString on a type mismatch, and ErrOverflow for numbers
+// beyond T
+func Get[T Scalar](ct *Container, path string) (T, error) {
+	var result T
+	err := ct.decodePath(path, func(d *decoder) error {
+		return d.scalar(reflect.ValueOf(&result).Elem())
+	})
+	return result, err
+}
+
+// GetArrayOf reads a whole array of scalars in a single cgo call
+func GetArrayOf[T Scalar](ct *Container, path string) ([]T, error) {
+	var result []T
+	err := ct.decodePath(path, func(d *decoder) error {
+		if d.buf[0] != C.JsonTagArray {
+			return ErrNotArray
+		}
+		d.buf = d.buf[1:]
+		result = make([]T, d.uint32())
+		for i := range result {
+			if err := d.scalar(reflect.ValueOf(&result[i]).Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetMapOf reads a whole object of scalars in a single cgo call
+func GetMapOf[T Scalar](ct *Container, path string) (map[string]T, error) {
+	var result map[string]T
+	err := ct.decodePath(path, func(d *decoder) error {
+		if d.buf[0] != C.JsonTagObject {
+			return ErrNotObject
+		}
+		d.buf = d.buf[1:]
+		count := d.uint32()
+		result = make(map[string]T, count)
+		for i := 0; i < count; i++ {
+			key := d.string()
+			var value T
+			if err := d.scalar(reflect.ValueOf(&value).Elem()); err != nil {
+				return err
+			}
+			result[key] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// decodePath encodes the value at path once and hands it to fn
+func (ct *Container) decodePath(path string, fn func(d *decoder) error) error {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
+	}
+	if path != "" {
+		var err error
+		if ct, err = ct.GetPathContainer(path); err != nil {
+			return err
+		}
+	}
+	var size C.size_t
+	buffer := C.ValEncode(ct.ct, &size)
+	defer C.free(unsafe.Pointer(buffer))
+
+	d := decoder{buf: unsafe.Slice((*byte)(unsafe.Pointer(buffer)), int(size))}
+	return fn(&d)
+}
+
+// scalar decodes one value into rv, skipping it on a mismatch
+func (d *decoder) scalar(rv reflect.Value) error {
+	kind := rv.Kind()
+	tag := d.buf[0]
+	switch {
+	case tag == C.JsonTagString && kind == reflect.String:
+		d.buf = d.buf[1:]
+		rv.SetString(d.string())
+		return nil
+	case (tag == C.JsonTagTrue || tag == C.JsonTagFalse) && kind == reflect.Bool:
+		d.buf = d.buf[1:]
+		rv.SetBool(tag == C.JsonTagTrue)
+		return nil
+	case tag == C.JsonTagInt64 || tag == C.JsonTagUint64 || tag == C.JsonTagDouble:
+		d.buf = d.buf[1:]
+		return scalarNumber(rv, tag, d.uint64())
+	case tag == C.JsonTagRawNumber:
+		d.buf = d.buf[1:]
+		tag, bits := rawNumberBits(d.string())
+		return scalarNumber(rv, tag, bits)
+	}
+	d.skip()
+	return scalarError(kind)
+}
+func scalarNumber(rv reflect.Value, tag byte, bits uint64) error {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if tag == C.JsonTagDouble {
+			return ErrNotInt
+		} else if tag == C.JsonTagUint64 || rv.OverflowInt(int64(bits)) {
+			return ErrOverflow
+		}
+		rv.SetInt(int64(bits))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if tag == C.JsonTagDouble || tag == C.JsonTagInt64 && int64(bits) < 0 {
+			return ErrNotUint
+		} else if rv.OverflowUint(bits) {
+			return ErrOverflow
+		}
+		rv.SetUint(bits)
+	case reflect.Float32, reflect.Float64:
+		f := numberFloat(tag, bits)
+		if rv.OverflowFloat(f) {
+			return ErrOverflow
+		}
+		rv.SetFloat(f)
+	default:
+		return scalarError(rv.Kind())
+	}
+	return nil
+}
+func scalarError(kind reflect.Kind) error {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ErrNotInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ErrNotUint
+	case reflect.Float32, reflect.Float64:
+		return ErrNotFloat
+	case reflect.Bool:
+		return ErrNotBool
+	default:
+		return ErrNotString
+	}
+}
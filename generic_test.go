@@ -0,0 +1,114 @@
+package rapidjson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+type testLevel uint8
+
+const testGeneric = `{"a": {"int": -5, "uint": 300, "float": 1.5, "bool": true, "str": "x", "big": 18446744073709551615},
+	"ints": [1, 2, -3], "floats": [1, 2.5], "bools": [true, false], "strs": ["a", "b"], "mixed": [1, "b"],
+	"levels": {"low": 1, "high": 200}, "prices": {"tea": 2.5, "cake": 4}}`
+
+func TestGet(t *testing.T) {
+	json, err := NewParsedStringJson(testGeneric)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	i, err := Get[int](ct, "a.int")
+	assert.Nil(t, err, "should not error on int")
+	assert.Equal(t, -5, i)
+	u, err := Get[uint16](ct, "a.uint")
+	assert.Nil(t, err, "should not error on uint16")
+	assert.Equal(t, uint16(300), u)
+	f, err := Get[float32](ct, "a.float")
+	assert.Nil(t, err, "should not error on float32")
+	assert.Equal(t, float32(1.5), f)
+	f64, err := Get[float64](ct, "a.int")
+	assert.Nil(t, err, "should not error on int as float")
+	assert.Equal(t, -5.0, f64)
+	b, err := Get[bool](ct, "a.bool")
+	assert.Nil(t, err, "should not error on bool")
+	assert.True(t, b)
+	s, err := Get[string](ct.GetMemberOrNil("a"), "str")
+	assert.Nil(t, err, "should not error on string")
+	assert.Equal(t, "x", s)
+	big, err := Get[uint64](ct, "a.big")
+	assert.Nil(t, err, "should not error on uint64")
+	assert.Equal(t, uint64(18446744073709551615), big)
+	level, err := Get[testLevel](ct.GetMemberOrNil("a").GetMemberOrNil("int"), "")
+	assert.Equal(t, ErrNotUint, err)
+	assert.Equal(t, testLevel(0), level)
+
+	_, err = Get[int8](ct, "a.uint")
+	assert.Equal(t, ErrOverflow, err)
+	_, err = Get[int64](ct, "a.big")
+	assert.Equal(t, ErrOverflow, err)
+	_, err = Get[int](ct, "a.float")
+	assert.Equal(t, ErrNotInt, err)
+	_, err = Get[bool](ct, "a.str")
+	assert.Equal(t, ErrNotBool, err)
+	_, err = Get[string](ct, "a.int")
+	assert.Equal(t, ErrNotString, err)
+	_, err = Get[float64](ct, "a")
+	assert.Equal(t, ErrNotFloat, err)
+	_, err = Get[int](ct, "a.missing")
+	assert.Equal(t, ErrPathNotFound, err)
+	_, err = Get[int](nil, "a")
+	assert.Equal(t, ErrPathNotFound, err)
+}
+
+func TestGetArrayAndMapOf(t *testing.T) {
+	json, err := NewParsedStringJson(testGeneric)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	ints, err := GetArrayOf[int32](ct, "ints")
+	assert.Nil(t, err, "should not error on ints")
+	assert.Equal(t, []int32{1, 2, -3}, ints)
+	floats, err := GetArrayOf[float64](ct, "floats")
+	assert.Nil(t, err, "should not error on floats")
+	assert.Equal(t, []float64{1, 2.5}, floats)
+	bools, err := GetArrayOf[bool](ct, "bools")
+	assert.Nil(t, err, "should not error on bools")
+	assert.Equal(t, []bool{true, false}, bools)
+	strs, err := GetArrayOf[string](ct.GetMemberOrNil("strs"), "")
+	assert.Nil(t, err, "should not error on strings")
+	assert.Equal(t, []string{"a", "b"}, strs)
+
+	_, err = GetArrayOf[uint](ct, "ints")
+	assert.Equal(t, ErrNotUint, err)
+	_, err = GetArrayOf[int](ct, "mixed")
+	assert.Equal(t, ErrNotInt, err)
+	_, err = GetArrayOf[int](ct, "a")
+	assert.Equal(t, ErrNotArray, err)
+
+	levels, err := GetMapOf[testLevel](ct, "levels")
+	assert.Nil(t, err, "should not error on levels")
+	assert.Equal(t, map[string]testLevel{"low": 1, "high": 200}, levels)
+	prices, err := GetMapOf[float64](ct, "prices")
+	assert.Nil(t, err, "should not error on prices")
+	assert.Equal(t, map[string]float64{"tea": 2.5, "cake": 4}, prices)
+
+	_, err = GetMapOf[int8](ct, "levels")
+	assert.Equal(t, ErrOverflow, err)
+	_, err = GetMapOf[int](ct, "ints")
+	assert.Equal(t, ErrNotObject, err)
+}
+
+func TestGetLossless(t *testing.T) {
+	json, err := NewParsedStringJsonWithOptions(`{"ids": [1, 18446744073709551615], "big": 123456789012345678901234567890}`, ParseOptions{LosslessNumbers: true})
+	assert.Nil(t, err, "should not error on lossless parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	ids, err := GetArrayOf[uint64](ct, "ids")
+	assert.Nil(t, err, "should not error on raw ids")
+	assert.Equal(t, []uint64{1, 18446744073709551615}, ids)
+	_, err = Get[int64](ct, "big")
+	assert.Equal(t, ErrNotInt, err)
+}
@@ -0,0 +1,85 @@
+package rapidjson
+
+// #include "rjwrapper.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrDocLeaked = errors.New("Doc not freed")
+
+	autoFree atomic.Bool
+	tracking atomic.Bool
+	liveMu   sync.Mutex
+	liveDocs = map[C.JsonDoc][]byte{} // keyed by the C pointer so tracked Docs can still be collected
+)
+
+// SetAutoFree makes Docs created from now on free themselves once
+// unreachable, as a safety net for a forgotten Free. Free stays the way to
+// release memory on time, the GC can't see C++ memory and may wait long
+func SetAutoFree(enabled bool) {
+	autoFree.Store(enabled)
+}
+
+// SetLeakTracking records the creation stack of every Doc created from now
+// on until it's freed, see LiveDocs and CheckLeaks. It's meant for tests and
+// debugging, capturing stacks is slow
+func SetLeakTracking(enabled bool) {
+	tracking.Store(enabled)
+}
+
+// LiveDocs returns the creation stacks of tracked Docs not freed yet
+func LiveDocs() []string {
+	liveMu.Lock()
+	defer liveMu.Unlock()
+	stacks := make([]string, 0, len(liveDocs))
+	for _, stack := range liveDocs {
+		stacks = append(stacks, string(stack))
+	}
+	return stacks
+}
+
+// CheckLeaks returns ErrDocLeaked with the creation stacks of tracked Docs
+// not freed yet, or nil
+func CheckLeaks() error {
+	stacks := LiveDocs()
+	if len(stacks) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("%d live", len(stacks))
+	for _, stack := range stacks {
+		msg += "\n\n" + stack
+	}
+	return fmt.Errorf("%w: %s", ErrDocLeaked, msg)
+}
+
+// init allocates the C document, autoFree needs json to be the start of its
+// allocation so Docs embedded in other values can't use it
+func (json *Doc) init(opts DocOptions, finalize bool) {
+	json.json = C.JsonInitAllocator(opts.kind(), C.size_t(max(opts.InitialBuffer, 0)))
+	if opts.CheckStale {
+		json.checkStale = true
+		json.changes = map[C.JsonVal]uint64{}
+	}
+	if tracking.Load() {
+		liveMu.Lock()
+		liveDocs[json.json] = debug.Stack()
+		liveMu.Unlock()
+	}
+	if finalize && autoFree.Load() {
+		runtime.SetFinalizer(json, (*Doc).Free)
+		json.finalizer = true
+	}
+}
+func untrack(doc C.JsonDoc) {
+	liveMu.Lock()
+	delete(liveDocs, doc)
+	liveMu.Unlock()
+}
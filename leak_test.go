@@ -0,0 +1,102 @@
+package rapidjson
+
+import (
+	encjson "encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func TestLeakTracking(t *testing.T) {
+	SetLeakTracking(true)
+	defer SetLeakTracking(false)
+
+	json, err := NewParsedStringJson(`{"a": [1, 2]}`)
+	assert.Nil(t, err, "should not error on parsing")
+	copied := json.GetContainer().GetCopy()
+	assert.Equal(t, `[1,2]`, copied.GetMemberOrNil("a").String())
+
+	err = CheckLeaks()
+	assert.True(t, errors.Is(err, ErrDocLeaked))
+	assert.True(t, strings.Contains(err.Error(), "2 live"))
+	assert.True(t, strings.Contains(err.Error(), "TestLeakTracking"))
+	assert.Equal(t, 2, len(LiveDocs()))
+
+	json.Free()
+	json.Free()
+	assert.Equal(t, 1, len(LiveDocs()))
+	copied.GetDoc().Free()
+	assert.Nil(t, CheckLeaks())
+}
+
+func TestAutoFree(t *testing.T) {
+	SetLeakTracking(true)
+	defer SetLeakTracking(false)
+	SetAutoFree(true)
+	defer SetAutoFree(false)
+
+	func() {
+		json, err := NewParsedStringJson(`{"a": "forgotten"}`)
+		assert.Nil(t, err, "should not error on parsing")
+		str, _ := json.GetContainer().GetMemberOrNil("a").GetString()
+		assert.Equal(t, "forgotten", str)
+
+		built := NewDoc()
+		assert.Nil(t, built.GetContainerNewObj().AddValue("a", 1))
+		assert.Equal(t, `{"a":1}`, built.String())
+	}()
+	kept := NewDoc()
+	defer kept.Free()
+
+	// finalizers run on their own goroutine after a GC
+	for i := 0; i < 100 && len(LiveDocs()) > 1; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	stacks := LiveDocs()
+	assert.Equal(t, 1, len(stacks))
+	assert.True(t, len(stacks) == 1 && strings.Contains(stacks[0], "leak_test.go"))
+}
+
+func TestFreeEmbeddedDoc(t *testing.T) {
+	SetAutoFree(true)
+	defer SetAutoFree(false)
+
+	var s struct {
+		A int
+		D Doc
+	}
+	assert.Nil(t, encjson.Unmarshal([]byte(`{"A": 1, "D": {"b": [1, 2]}}`), &s))
+	assert.Equal(t, `{"b":[1,2]}`, s.D.String())
+	s.D.Free()
+	s.D.Free()
+}
+
+func TestAutoFreeDuringCall(t *testing.T) {
+	SetAutoFree(true)
+	defer SetAutoFree(false)
+
+	big := `[` + strings.Repeat(`{"a":"some text","b":[1,2.5,true]},`, 5000) + `null]`
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				runtime.GC()
+			}
+		}
+	}()
+
+	// the Doc is unreachable once its Container is taken, calls keep it alive
+	for i := 0; i < 20; i++ {
+		json, _ := NewParsedStringJson(big)
+		assert.Equal(t, big, json.GetContainer().String())
+	}
+}
@@ -0,0 +1,884 @@
+package rapidjson
+
+// #include <stdlib.h>
+// #include "rjwrapper.h"
+import "C"
+import "unsafe"
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNoDoc = errors.New("Container has no Doc")
+
+	marshalerType       = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	containerType       = reflect.TypeOf((*Container)(nil))
+	docType             = reflect.TypeOf((*Doc)(nil))
+)
+
+// Marshal returns the JSON encoding of v. Structs follow encoding/json:
+// exported fields, json:"name,omitempty,string" tags and embedded structs
+func Marshal(v interface{}) ([]byte, error) {
+	doc := NewDoc()
+	defer doc.Free()
+	err := doc.GetContainer().SetValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	_, err = doc.WriteTo(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal parses data and stores the result in the value v points to,
+// following the same rules as encoding/json
+func Unmarshal(data []byte, v interface{}) error {
+	return UnmarshalWithOptions(data, v, ParseOptions{})
+}
+
+// UnmarshalWithOptions is Unmarshal with parse flags. LosslessNumbers gives
+// json.Number and json.Unmarshaler values such as big.Int the exact text
+func UnmarshalWithOptions(data []byte, v interface{}, opts ParseOptions) error {
+	doc := NewDoc()
+	defer doc.Free()
+	err := doc.ParseWithOptions(data, opts)
+	if err != nil {
+		return err
+	}
+	return doc.GetContainer().Decode(v)
+}
+
+// Decode stores the tree in the value v points to, like Unmarshal. Numbers
+// decoded into interface{} are float64
+func (ct *Container) Decode(v interface{}) error {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	var size C.size_t
+	buffer := C.ValEncode(ct.ct, &size)
+	defer C.free(unsafe.Pointer(buffer))
+
+	d := decoder{buf: unsafe.Slice((*byte)(unsafe.Pointer(buffer)), int(size)), opts: ConvertOptions{Numbers: NumberFloat64}}
+	d.into(rv.Elem())
+	return d.err
+}
+
+// MarshalJSON lets Containers be embedded in values encoded by
+// encoding/json, a nil Container is null
+func (ct *Container) MarshalJSON() ([]byte, error) {
+	if ct == nil || ct.ct == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	_, err := ct.FormatTo(&buf, WriteOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces the value with data, allocated by the Container's
+// Doc. A zero Container, as allocated by encoding/json for a *Container
+// field, becomes the root of a new Doc that should be freed through GetDoc
+func (ct *Container) UnmarshalJSON(data []byte) error {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return ErrNoDoc
+	} else if ct.doc == nil {
+		json := NewDoc()
+		if err := json.Parse(data); err != nil {
+			json.Free()
+			return err
+		}
+		*ct = *json.GetContainer()
+		return nil
+	} else if ct.stale() {
+		return ErrStaleContainer
+	}
+	changed(ct)
+	return ct.setRaw(data, ParseOptions{})
+}
+
+// MarshalText is the compact JSON text, for text based encoders such as
+// log/slog
+func (ct *Container) MarshalText() ([]byte, error) {
+	return ct.MarshalJSON()
+}
+
+func (json *Doc) MarshalJSON() ([]byte, error) {
+	if json == nil || json.json == nil {
+		return []byte("null"), nil
+	}
+	return json.GetContainer().MarshalJSON()
+}
+
+// UnmarshalJSON parses data into the Doc. A zero Doc, as allocated by
+// encoding/json for a *Doc field, is initialized first and should be freed
+// like any other, it may be embedded so it's never freed automatically
+func (json *Doc) UnmarshalJSON(data []byte) error {
+	if json.json == nil {
+		json.init(DocOptions{}, false)
+	}
+	return json.Parse(data)
+}
+func (json *Doc) MarshalText() ([]byte, error) {
+	return json.MarshalJSON()
+}
+
+// setRaw parses data and copies it in with the Container's allocator
+func (ct *Container) setRaw(data []byte, opts ParseOptions) error {
+	defer runtime.KeepAlive(ct)
+	doc, err := NewParsedJsonWithOptions(data, opts)
+	defer doc.Free()
+	if err != nil {
+		return err
+	}
+	C.CopyFrom(ct.doc.json, ct.ct, C.JsonVal(unsafe.Pointer(doc.json)))
+	return nil
+}
+
+// field is a struct field as encoding/json sees it, index leads through
+// embedded structs
+type field struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool
+}
+
+type structFields struct {
+	list   []field
+	byName map[string]*field
+	byFold map[string]*field // lower case names, for case insensitive decoding
+}
+
+var fieldCache sync.Map // map[reflect.Type]*structFields
+
+func cachedFields(t reflect.Type) *structFields {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(*structFields)
+	}
+	fields, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fields.(*structFields)
+}
+
+// typeFields walks embedded structs breadth first. A name at a shallower
+// depth hides deeper ones, a tagged name wins at the same depth and the
+// name is dropped if that's still ambiguous
+func typeFields(t reflect.Type) *structFields {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var current []embedded
+	next := []embedded{{typ: t}}
+	visited := map[reflect.Type]bool{}
+	var list []field
+
+	for len(next) > 0 {
+		current, next = next, nil
+		count := map[reflect.Type]int{}
+		for _, e := range current {
+			count[e.typ]++
+		}
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(append([]int{}, e.index...), i)
+
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+				f := field{name: name, index: index, tagged: name != ""}
+				if name == "" {
+					f.name = sf.Name
+				}
+				for _, opt := range strings.Split(opts, ",") {
+					switch opt {
+					case "omitempty":
+						f.omitEmpty = true
+					case "string":
+						switch ft.Kind() {
+						case reflect.Bool, reflect.String,
+							reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+							reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+							reflect.Float32, reflect.Float64:
+							f.quoted = true
+						}
+					}
+				}
+				list = append(list, f)
+				// the same struct embedded twice at one depth hides its fields
+				if count[e.typ] > 1 {
+					list = append(list, f)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].name != list[j].name {
+			return list[i].name < list[j].name
+		}
+		if len(list[i].index) != len(list[j].index) {
+			return len(list[i].index) < len(list[j].index)
+		}
+		return list[i].tagged && !list[j].tagged
+	})
+	out := list[:0]
+	for i := 0; i < len(list); {
+		j := i + 1
+		for j < len(list) && list[j].name == list[i].name {
+			j++
+		}
+		if j-i == 1 || len(list[i].index) < len(list[i+1].index) || list[i].tagged != list[i+1].tagged {
+			out = append(out, list[i])
+		}
+		i = j
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].index, out[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	fields := &structFields{list: out, byName: map[string]*field{}, byFold: map[string]*field{}}
+	for i := range out {
+		f := &out[i]
+		fields.byName[f.name] = f
+		if fold := strings.ToLower(f.name); fields.byFold[fold] == nil {
+			fields.byFold[fold] = f
+		}
+	}
+	return fields
+}
+
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// encoding
+
+// setMarshaler writes values implementing json.Marshaler or
+// encoding.TextMarshaler, ok is false for other values. Like encoding/json,
+// pointer receiver methods are only used when rv is addressable
+func (ct *Container) setMarshaler(rv reflect.Value) (ok bool, err error) {
+	if rv.Kind() == reflect.Interface {
+		return false, nil
+	}
+	if rv.Kind() != reflect.Pointer && rv.CanAddr() {
+		if pt := reflect.PointerTo(rv.Type()); pt.Implements(marshalerType) || pt.Implements(textMarshalerType) {
+			rv = rv.Addr()
+		}
+	}
+	t := rv.Type()
+	if !t.Implements(marshalerType) && !t.Implements(textMarshalerType) || !rv.CanInterface() {
+		return false, nil
+	}
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		C.SetNull(ct.ct)
+		return true, nil
+	}
+
+	// copy Containers and Docs directly rather than through their text
+	switch t {
+	case containerType:
+		if item := rv.Interface().(*Container); item.stale() {
+			return true, ErrStaleContainer
+		} else if item.ct != nil {
+			C.CopyFrom(ct.doc.json, ct.ct, item.ct)
+		} else {
+			C.SetNull(ct.ct)
+		}
+		return true, nil
+	case docType:
+		if item := rv.Interface().(*Doc); item.json != nil {
+			C.CopyFrom(ct.doc.json, ct.ct, C.JsonVal(unsafe.Pointer(item.json)))
+		} else {
+			C.SetNull(ct.ct)
+		}
+		return true, nil
+	}
+
+	if m, isMarshaler := rv.Interface().(json.Marshaler); isMarshaler {
+		var b []byte
+		b, err = m.MarshalJSON()
+		if err == nil {
+			// numbers keep their text, as encoding/json would copy them
+			err = ct.setRaw(b, ParseOptions{LosslessNumbers: true})
+		}
+	} else {
+		var b []byte
+		b, err = rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err == nil {
+			cStr, size := bytesToC(b)
+			C.SetString(ct.doc.json, ct.ct, cStr, size)
+		}
+	}
+	if err != nil {
+		return true, &json.MarshalerError{Type: t, Err: err}
+	}
+	return true, nil
+}
+
+func (ct *Container) setStruct(rv reflect.Value, st *setState) error {
+	fields := cachedFields(rv.Type())
+	ct.ct = C.InitObj(ct.ct)
+	for i := range fields.list {
+		f := &fields.list[i]
+		fv, ok := fieldByIndex(rv, f.index, false)
+		if !ok || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		cStr, size := stringToC(f.name)
+		item := ct.derive(C.AddStrMemberNull(ct.doc.json, ct.ct, cStr, size))
+		var err error
+		if f.quoted {
+			err = item.setQuoted(fv, st)
+		} else {
+			err = item.setReflect(fv, st)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndex follows embedded struct pointers, which are skipped when
+// nil or allocated when alloc is set
+func fieldByIndex(rv reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !alloc || !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// setQuoted writes the ",string" form of a scalar, its JSON text as a string
+func (ct *Container) setQuoted(rv reflect.Value, st *setState) error {
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			C.SetNull(ct.ct)
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	var str string
+	switch rv.Kind() {
+	case reflect.Bool:
+		str = strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		str = strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		str = strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return ErrNanOrInf
+		}
+		str = strconv.FormatFloat(f, 'g', -1, rv.Type().Bits())
+	case reflect.String:
+		err := ct.setReflect(rv, st)
+		if err != nil {
+			return err
+		}
+		str = ct.String()
+	}
+	cStr, size := stringToC(str)
+	C.SetString(ct.doc.json, ct.ct, cStr, size)
+	return nil
+}
+
+// decoding
+func (d *decoder) mismatch(value string, rv reflect.Value) {
+	if d.err == nil {
+		d.err = &json.UnmarshalTypeError{Value: value, Type: rv.Type(), Field: strings.Join(d.fields, ".")}
+	}
+}
+
+// into decodes the next value into rv. Like encoding/json, a mismatched
+// value is skipped and reported after the rest has been decoded
+func (d *decoder) into(rv reflect.Value) {
+	tag := d.buf[0]
+	if d.intoUnmarshaler(rv, tag) {
+		return
+	}
+	if tag == C.JsonTagNull {
+		d.buf = d.buf[1:]
+		switch rv.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			rv.SetZero()
+		}
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		d.into(rv.Elem())
+		return
+	case reflect.Interface:
+		if !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer && !rv.Elem().IsNil() {
+			d.into(rv.Elem())
+		} else if rv.NumMethod() == 0 {
+			rv.Set(reflect.ValueOf(d.value()))
+		} else {
+			d.mismatch(tagNames[tag], rv)
+			d.skip()
+		}
+		return
+	}
+
+	d.buf = d.buf[1:]
+	switch tag {
+	case C.JsonTagFalse, C.JsonTagTrue:
+		if rv.Kind() == reflect.Bool {
+			rv.SetBool(tag == C.JsonTagTrue)
+		} else {
+			d.mismatch("bool", rv)
+		}
+	case C.JsonTagInt64, C.JsonTagUint64, C.JsonTagDouble:
+		d.intoNumber(rv, tag, d.uint64())
+	case C.JsonTagRawNumber:
+		text := d.string()
+		if rv.Type() == numberType {
+			rv.SetString(text)
+		} else {
+			tag, bits := rawNumberBits(text)
+			d.intoNumber(rv, tag, bits)
+		}
+	case C.JsonTagString:
+		size := d.uint32()
+		str := d.buf[:size]
+		d.buf = d.buf[size:]
+		switch {
+		case rv.Type() == numberType:
+			if isNumber(string(str)) {
+				rv.SetString(string(str))
+			} else {
+				d.mismatch("string", rv)
+			}
+		case rv.Kind() == reflect.String:
+			rv.SetString(string(str))
+		case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+			b := make([]byte, base64.StdEncoding.DecodedLen(len(str)))
+			n, err := base64.StdEncoding.Decode(b, str)
+			if err != nil {
+				if d.err == nil {
+					d.err = err
+				}
+				return
+			}
+			rv.SetBytes(b[:n])
+		default:
+			d.mismatch("string", rv)
+		}
+	case C.JsonTagArray:
+		count := d.uint32()
+		switch rv.Kind() {
+		case reflect.Slice:
+			s := reflect.MakeSlice(rv.Type(), count, count)
+			for i := 0; i < count; i++ {
+				d.into(s.Index(i))
+			}
+			rv.Set(s)
+		case reflect.Array:
+			for i := 0; i < count; i++ {
+				if i < rv.Len() {
+					d.into(rv.Index(i))
+				} else {
+					d.skip()
+				}
+			}
+			for i := count; i < rv.Len(); i++ {
+				rv.Index(i).SetZero()
+			}
+		default:
+			d.mismatch("array", rv)
+			for ; count > 0; count-- {
+				d.skip()
+			}
+		}
+	case C.JsonTagObject:
+		count := d.uint32()
+		switch rv.Kind() {
+		case reflect.Map:
+			d.intoMap(rv, count)
+		case reflect.Struct:
+			d.intoStruct(rv, count)
+		default:
+			d.mismatch("object", rv)
+			for ; count > 0; count-- {
+				d.buf = d.buf[d.uint32():]
+				d.skip()
+			}
+		}
+	}
+}
+
+// intoUnmarshaler decodes into values whose address implements
+// json.Unmarshaler or encoding.TextUnmarshaler, ok is false for other values.
+// Pointers are left to into, which allocates or clears them first
+func (d *decoder) intoUnmarshaler(rv reflect.Value, tag byte) (ok bool) {
+	if rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface || !rv.CanAddr() {
+		return false
+	}
+	pv := rv.Addr()
+	if !pv.CanInterface() {
+		return false
+	}
+	var err error
+	if u, isUnmarshaler := pv.Interface().(json.Unmarshaler); isUnmarshaler {
+		err = u.UnmarshalJSON(d.raw(nil))
+	} else if u, isUnmarshaler := pv.Interface().(encoding.TextUnmarshaler); isUnmarshaler {
+		switch tag {
+		case C.JsonTagNull:
+			// null leaves text values alone
+			d.buf = d.buf[1:]
+		case C.JsonTagString:
+			d.buf = d.buf[1:]
+			size := d.uint32()
+			err = u.UnmarshalText(d.buf[:size:size])
+			d.buf = d.buf[size:]
+		default:
+			d.mismatch(tagNames[tag], rv)
+			d.skip()
+		}
+	} else {
+		return false
+	}
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+	return true
+}
+
+// raw consumes the next value and appends its JSON text to b, for
+// json.Unmarshaler
+func (d *decoder) raw(b []byte) []byte {
+	tag := d.buf[0]
+	d.buf = d.buf[1:]
+	switch tag {
+	case C.JsonTagNull:
+		return append(b, "null"...)
+	case C.JsonTagFalse:
+		return append(b, "false"...)
+	case C.JsonTagTrue:
+		return append(b, "true"...)
+	case C.JsonTagInt64, C.JsonTagUint64, C.JsonTagDouble:
+		return append(b, numberText(tag, d.uint64())...)
+	case C.JsonTagRawNumber:
+		size := d.uint32()
+		b = append(b, d.buf[:size]...)
+		d.buf = d.buf[size:]
+		return b
+	case C.JsonTagString:
+		size := d.uint32()
+		b = appendQuoted(b, d.buf[:size])
+		d.buf = d.buf[size:]
+		return b
+	case C.JsonTagArray:
+		b = append(b, '[')
+		for i, count := 0, d.uint32(); i < count; i++ {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = d.raw(b)
+		}
+		return append(b, ']')
+	default:
+		b = append(b, '{')
+		for i, count := 0, d.uint32(); i < count; i++ {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			size := d.uint32()
+			b = appendQuoted(b, d.buf[:size])
+			d.buf = d.buf[size:]
+			b = append(b, ':')
+			b = d.raw(b)
+		}
+		return append(b, '}')
+	}
+}
+
+// appendQuoted appends str as a JSON string, escaping only what JSON
+// requires
+func appendQuoted(b []byte, str []byte) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for _, c := range str {
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
+
+var tagNames = map[byte]string{
+	C.JsonTagFalse:     "bool",
+	C.JsonTagTrue:      "bool",
+	C.JsonTagInt64:     "number",
+	C.JsonTagUint64:    "number",
+	C.JsonTagDouble:    "number",
+	C.JsonTagString:    "string",
+	C.JsonTagArray:     "array",
+	C.JsonTagObject:    "object",
+	C.JsonTagRawNumber: "number",
+}
+
+func (d *decoder) intoNumber(rv reflect.Value, tag byte, bits uint64) {
+	if rv.Type() == numberType {
+		rv.SetString(numberText(tag, bits))
+		return
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if tag != C.JsonTagInt64 || rv.OverflowInt(int64(bits)) {
+			d.mismatch("number "+numberText(tag, bits), rv)
+			return
+		}
+		rv.SetInt(int64(bits))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if tag == C.JsonTagDouble || tag == C.JsonTagInt64 && int64(bits) < 0 || rv.OverflowUint(bits) {
+			d.mismatch("number "+numberText(tag, bits), rv)
+			return
+		}
+		rv.SetUint(bits)
+	case reflect.Float32, reflect.Float64:
+		f := numberFloat(tag, bits)
+		if rv.OverflowFloat(f) {
+			d.mismatch("number "+numberText(tag, bits), rv)
+			return
+		}
+		rv.SetFloat(f)
+	default:
+		d.mismatch("number", rv)
+	}
+}
+
+func (d *decoder) intoMap(rv reflect.Value, count int) {
+	t := rv.Type()
+	textKey := reflect.PointerTo(t.Key()).Implements(textUnmarshalerType)
+	switch t.Key().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if textKey {
+			break
+		}
+		d.mismatch("object", rv)
+		for ; count > 0; count-- {
+			d.buf = d.buf[d.uint32():]
+			d.skip()
+		}
+		return
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(t, count))
+	}
+
+	for ; count > 0; count-- {
+		key := d.string()
+		kv := reflect.New(t.Key()).Elem()
+		switch {
+		case textKey:
+			err := kv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key))
+			if err != nil {
+				if d.err == nil {
+					d.err = err
+				}
+				d.skip()
+				continue
+			}
+		case kv.Kind() == reflect.String:
+			kv.SetString(key)
+		case kv.CanInt():
+			n, err := strconv.ParseInt(key, 10, 64)
+			if err != nil || kv.OverflowInt(n) {
+				d.mismatch("number "+key, kv)
+				d.skip()
+				continue
+			}
+			kv.SetInt(n)
+		default:
+			n, err := strconv.ParseUint(key, 10, 64)
+			if err != nil || kv.OverflowUint(n) {
+				d.mismatch("number "+key, kv)
+				d.skip()
+				continue
+			}
+			kv.SetUint(n)
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		d.into(elem)
+		rv.SetMapIndex(kv, elem)
+	}
+}
+
+func (d *decoder) intoStruct(rv reflect.Value, count int) {
+	fields := cachedFields(rv.Type())
+	for ; count > 0; count-- {
+		key := d.string()
+		f := fields.byName[key]
+		if f == nil {
+			f = fields.byFold[strings.ToLower(key)]
+		}
+		if f == nil {
+			d.skip()
+			continue
+		}
+		fv, ok := fieldByIndex(rv, f.index, true)
+		if !ok {
+			d.skip()
+			continue
+		}
+		d.fields = append(d.fields, f.name)
+		if f.quoted {
+			d.intoQuoted(fv)
+		} else {
+			d.into(fv)
+		}
+		d.fields = d.fields[:len(d.fields)-1]
+	}
+}
+
+// intoQuoted decodes the ",string" form of a scalar, its JSON text as a
+// string
+func (d *decoder) intoQuoted(rv reflect.Value) {
+	switch d.buf[0] {
+	case C.JsonTagNull:
+		d.into(rv)
+		return
+	case C.JsonTagString:
+	default:
+		d.mismatch(tagNames[d.buf[0]], rv)
+		d.skip()
+		return
+	}
+	d.buf = d.buf[1:]
+	str := d.string()
+
+	doc, err := NewParsedStringJson(str)
+	defer doc.Free()
+	if t := doc.GetContainer().GetType(); err != nil || t == TypeArray || t == TypeObject {
+		if d.err == nil {
+			d.err = fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", str, rv.Type())
+		}
+		return
+	}
+	var size C.size_t
+	buffer := C.ValEncode(C.JsonVal(unsafe.Pointer(doc.json)), &size)
+	defer C.free(unsafe.Pointer(buffer))
+	inner := decoder{buf: unsafe.Slice((*byte)(unsafe.Pointer(buffer)), int(size)), opts: d.opts, fields: d.fields}
+	inner.into(rv)
+	if d.err == nil {
+		d.err = inner.err
+	}
+}
+
+// numberText is the text of a ValEncode number
+func numberText(tag byte, bits uint64) string {
+	switch tag {
+	case C.JsonTagInt64:
+		return strconv.FormatInt(int64(bits), 10)
+	case C.JsonTagUint64:
+		return strconv.FormatUint(bits, 10)
+	default:
+		return strconv.FormatFloat(math.Float64frombits(bits), 'g', -1, 64)
+	}
+}
+func numberFloat(tag byte, bits uint64) float64 {
+	switch tag {
+	case C.JsonTagInt64:
+		return float64(int64(bits))
+	case C.JsonTagUint64:
+		return float64(bits)
+	default:
+		return math.Float64frombits(bits)
+	}
+}
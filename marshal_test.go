@@ -0,0 +1,306 @@
+package rapidjson
+
+import (
+	encjson "encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+type testBase struct {
+	ID      int64  `json:"id"`
+	Created string `json:"created,omitempty"`
+}
+
+type testInner struct {
+	Sub1 float64 `json:"sub1"`
+	Sub2 bool    `json:"sub2"`
+	Sub3 *int    `json:"sub3"`
+}
+
+type testRecord struct {
+	testBase
+	*Extra
+	Member1 int               `json:"member1"`
+	Member2 []int             `json:"member2"`
+	Member3 testInner         `json:"member3"`
+	Member4 string            `json:"member4"`
+	Count   uint16            `json:"count,string"`
+	Ratio   float32           `json:"ratio,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Next    *testRecord       `json:"next,omitempty"`
+	Any     interface{}       `json:"any"`
+	Skipped string            `json:"-"`
+	NoTag   string
+	private string
+}
+
+type Extra struct {
+	Note string `json:"note"`
+}
+
+func TestMarshal(t *testing.T) {
+	record := testRecord{
+		testBase: testBase{ID: 7},
+		Member1:  12345,
+		Member2:  []int{1, 2, 3},
+		Member3:  testInner{Sub1: 1.234, Sub2: true},
+		Member4:  "rapidjson is awesome!",
+		Count:    3,
+		Next:     &testRecord{Member3: testInner{Sub1: 0.5}, Member4: "next"},
+		Any:      []interface{}{"a", 1.5},
+		Skipped:  "skipped",
+		NoTag:    "no tag",
+		private:  "private",
+	}
+	out, err := Marshal(record)
+	assert.Nil(t, err, "should not error on marshal")
+	std, err := encjson.Marshal(record)
+	assert.Nil(t, err, "should not error on encoding/json marshal")
+	assert.Equal(t, string(std), string(out))
+
+	// embedded pointer fields are promoted when set
+	record.Extra = &Extra{Note: "note"}
+	record.Tags = map[string]string{"b": "2", "a": "1"}
+	out, err = Marshal(&record)
+	assert.Nil(t, err, "should not error on marshal pointer")
+	std, _ = encjson.Marshal(&record)
+	assert.Equal(t, string(std), string(out))
+
+	json := NewDoc()
+	defer json.Free()
+	ct := json.GetContainerNewObj()
+	assert.Nil(t, ct.AddValue("inner", testInner{Sub1: 2}))
+	assert.Equal(t, `{"inner":{"sub1":2.0,"sub2":false,"sub3":null}}`, json.String())
+
+	_, err = Marshal(map[string]interface{}{"f": func() {}})
+	assert.Equal(t, ErrBadType, err)
+
+	// cycles error like encoding/json, deep values without one don't
+	cyclic := &testRecord{Member4: "cyclic"}
+	cyclic.Next = cyclic
+	_, err = Marshal(cyclic)
+	var unsupported *encjson.UnsupportedValueError
+	assert.True(t, errors.As(err, &unsupported), "should error on a cycle")
+	deep := &testRecord{}
+	for i := 0; i < 1500; i++ {
+		deep = &testRecord{Next: deep}
+	}
+	_, err = Marshal(deep)
+	assert.Nil(t, err, "should not error on deep values")
+}
+
+func TestUnmarshal(t *testing.T) {
+	input := []byte(`{"id": 7, "note": "note", "member1": 12345, "member2": [1, 2, 3],
+        "member3": {"sub1": 1.234, "sub2": true, "sub3": 5}, "MEMBER4": "rapidjson is awesome!",
+        "count": "3", "tags": {"a": "1"}, "next": {"member4": "next"}, "any": ["a", 1.5, {"b": null}],
+        "notag": "no tag", "unknown": [1, {"x": 2}]}`)
+	var record testRecord
+	err := Unmarshal(input, &record)
+	assert.Nil(t, err, "should not error on unmarshal")
+	var std testRecord
+	assert.Nil(t, encjson.Unmarshal(input, &std))
+	assert.Equal(t, std, record)
+	assert.Equal(t, "note", record.Note)
+	assert.Equal(t, 5, *record.Member3.Sub3)
+	assert.Equal(t, "next", record.Next.Member4)
+
+	// null leaves values alone, except pointers, maps, slices and interfaces
+	err = Unmarshal([]byte(`{"id": null, "member2": null, "next": null, "count": null}`), &record)
+	assert.Nil(t, err, "should not error on nulls")
+	assert.Equal(t, int64(7), record.ID)
+	assert.Nil(t, record.Member2)
+	assert.Nil(t, record.Next)
+	assert.Equal(t, uint16(3), record.Count)
+
+	var values struct {
+		Array  [2]int8
+		Bytes  []byte
+		Number encjson.Number
+		Keys   map[int]bool
+		Big    uint64
+	}
+	err = Unmarshal([]byte(`{"array": [1, 2, 3], "bytes": "aGk=", "number": 1.5, "keys": {"1": true}, "big": 18446744073709551615}`), &values)
+	assert.Nil(t, err, "should not error on values")
+	assert.Equal(t, [2]int8{1, 2}, values.Array)
+	assert.Equal(t, []byte("hi"), values.Bytes)
+	assert.Equal(t, encjson.Number("1.5"), values.Number)
+	assert.Equal(t, map[int]bool{1: true}, values.Keys)
+	assert.Equal(t, uint64(18446744073709551615), values.Big)
+
+	// from a Container
+	json, err := NewParsedStringJson(`{"data": {"sub1": 2.5, "sub2": true}}`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	var inner testInner
+	assert.Nil(t, json.GetContainer().GetMemberOrNil("data").Decode(&inner))
+	assert.Equal(t, testInner{Sub1: 2.5, Sub2: true}, inner)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var record testRecord
+	err := Unmarshal([]byte(`{"member1": "one", "member4": "four", "member3": {"sub2": 1}}`), &record)
+	var typeErr *encjson.UnmarshalTypeError
+	assert.True(t, errors.As(err, &typeErr), "should be an *UnmarshalTypeError")
+	assert.Equal(t, "member1", typeErr.Field)
+	assert.Equal(t, reflect.TypeOf(0), typeErr.Type)
+	// keeps decoding after a mismatch
+	assert.Equal(t, "four", record.Member4)
+
+	err = Unmarshal([]byte(`{"member3": {"sub2": 1}}`), &record)
+	assert.True(t, errors.As(err, &typeErr), "should error on nested mismatch")
+	assert.Equal(t, "member3.sub2", typeErr.Field)
+
+	var small struct{ N int8 }
+	err = Unmarshal([]byte(`{"n": 300}`), &small)
+	assert.True(t, errors.As(err, &typeErr), "should error on overflow")
+	assert.Equal(t, "number 300", typeErr.Value)
+
+	err = Unmarshal([]byte(`{"count": "x"}`), &record)
+	assert.NotNil(t, err, "should error on bad ,string value")
+
+	err = Unmarshal([]byte(`{"id": }`), &record)
+	assert.True(t, errors.Is(err, ErrJsonParse), "should error on bad json")
+
+	err = Unmarshal([]byte(`{}`), record)
+	var invalidErr *encjson.InvalidUnmarshalError
+	assert.True(t, errors.As(err, &invalidErr), "should error on non-pointer")
+}
+
+type testUpper string
+
+func (u testUpper) MarshalJSON() ([]byte, error) {
+	return []byte(`{"upper":"` + strings.ToUpper(string(u)) + `"}`), nil
+}
+func (u *testUpper) UnmarshalJSON(data []byte) error {
+	*u = testUpper(strings.ToLower(string(data)))
+	return nil
+}
+
+type testInterop struct {
+	Upper   testUpper            `json:"upper"`
+	Raw     encjson.RawMessage   `json:"raw"`
+	Time    time.Time            `json:"time"`
+	Doc     *Doc                 `json:"doc"`
+	Pointer Pointer              `json:"pointer"`
+	ByPtr   map[Pointer]int      `json:"byPtr"`
+	Ct      *Container           `json:"ct,omitempty"`
+	Nil     encjson.Marshaler    `json:"nil"`
+	Text    map[string]testUpper `json:"text,omitempty"`
+}
+
+func TestMarshalInterop(t *testing.T) {
+	doc, _ := NewParsedStringJson(`{"a": [1, "x"]}`)
+	defer doc.Free()
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	value := testInterop{
+		Upper:   "abc",
+		Raw:     encjson.RawMessage(`[1, {"b": 2}]`),
+		Time:    when,
+		Doc:     doc,
+		Pointer: NewPointerFromTokens("a", "0"),
+		ByPtr:   map[Pointer]int{NewPointerFromTokens("x"): 1},
+		Ct:      doc.GetContainer().GetMemberOrNil("a"),
+	}
+	out, err := Marshal(&value)
+	assert.Nil(t, err, "should not error on marshal")
+	std, err := encjson.Marshal(&value)
+	assert.Nil(t, err, "should not error on encoding/json marshal")
+	assert.Equal(t, string(std), string(out))
+	assert.Equal(t, `{"upper":{"upper":"ABC"},"raw":[1,{"b":2}],"time":"2020-01-02T03:04:05Z","doc":{"a":[1,"x"]},"pointer":"/a/0","byPtr":{"/x":1},"ct":[1,"x"],"nil":null}`, string(out))
+
+	// Containers need a Doc to decode into
+	target := NewDoc()
+	defer target.Free()
+	decoded := testInterop{Ct: target.NewContainer()}
+	err = Unmarshal(out, &decoded)
+	assert.Nil(t, err, "should not error on unmarshal")
+	defer decoded.Doc.Free()
+	assert.Equal(t, `[1,"x"]`, decoded.Ct.String())
+	assert.Equal(t, testUpper(`{"upper":"abc"}`), decoded.Upper)
+	assert.Equal(t, `[1,{"b":2}]`, string(decoded.Raw))
+	assert.True(t, when.Equal(decoded.Time), "should decode time")
+	assert.Equal(t, `{"a":[1,"x"]}`, decoded.Doc.String())
+	assert.Equal(t, "/a/0", decoded.Pointer.String())
+	assert.Equal(t, map[Pointer]int{NewPointerFromTokens("x"): 1}, decoded.ByPtr)
+
+	// text unmarshalers reject non-strings
+	err = Unmarshal([]byte(`{"pointer": 1}`), &decoded)
+	var typeErr *encjson.UnmarshalTypeError
+	assert.True(t, errors.As(err, &typeErr), "should error on number into Pointer")
+	err = Unmarshal([]byte(`{"pointer": "a/b"}`), &decoded)
+	assert.True(t, errors.Is(err, ErrBadPointer), "should return UnmarshalText error")
+
+	_, err = Marshal(testFailing{})
+	var marshalerErr *encjson.MarshalerError
+	assert.True(t, errors.As(err, &marshalerErr), "should wrap MarshalJSON error")
+	_, err = Marshal(testInvalid{})
+	assert.True(t, errors.Is(err, ErrJsonParse), "should error on invalid MarshalJSON output")
+}
+
+type testFailing struct{}
+
+func (testFailing) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("failing")
+}
+
+type testInvalid struct{}
+
+func (testInvalid) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":`), nil
+}
+
+func TestContainerJSONInterfaces(t *testing.T) {
+	json, _ := NewParsedStringJson(`{"id": 1, "tags": ["a", "b"]}`)
+	defer json.Free()
+	wrapper := struct {
+		Name string     `json:"name"`
+		Data *Container `json:"data"`
+		Root *Doc       `json:"root"`
+	}{"w", json.GetContainer().GetMemberOrNil("tags"), json}
+	out, err := encjson.Marshal(wrapper)
+	assert.Nil(t, err, "should not error on encoding/json marshal")
+	assert.Equal(t, `{"name":"w","data":["a","b"],"root":{"id":1,"tags":["a","b"]}}`, string(out))
+
+	text, err := json.GetContainer().GetMemberOrNil("id").MarshalText()
+	assert.Nil(t, err, "should not error on MarshalText")
+	assert.Equal(t, "1", string(text))
+
+	// decoding allocates a Doc, Containers decode in place with their Doc
+	var decoded struct {
+		Root *Doc `json:"root"`
+	}
+	err = encjson.Unmarshal([]byte(`{"root": {"x": [true, null]}}`), &decoded)
+	assert.Nil(t, err, "should not error on encoding/json unmarshal")
+	defer decoded.Root.Free()
+	assert.Equal(t, `{"x":[true,null]}`, decoded.Root.String())
+
+	tags := json.GetContainer().GetMemberOrNil("tags")
+	assert.Nil(t, encjson.Unmarshal([]byte(`{"replaced": 1.5}`), tags))
+	assert.Equal(t, `{"id":1,"tags":{"replaced":1.5}}`, json.String())
+
+	// zero Containers get a Doc of their own
+	var zeroCt struct {
+		Ct  *Container
+		Val Container
+	}
+	err = encjson.Unmarshal([]byte(`{"Ct": {"a": [1]}, "Val": "v"}`), &zeroCt)
+	assert.Nil(t, err, "should not error on encoding/json unmarshal")
+	assert.Equal(t, `{"a":[1]}`, zeroCt.Ct.String())
+	assert.Equal(t, `"v"`, zeroCt.Val.String())
+	assert.Equal(t, `{"a":[1]}`, zeroCt.Ct.GetDoc().String())
+	zeroCt.Ct.GetDoc().Free()
+	zeroCt.Val.GetDoc().Free()
+	var zero Container
+	assert.NotNil(t, zero.UnmarshalJSON([]byte(`{"a":`)))
+	assert.Nil(t, zero.GetDoc())
+
+	var nilCt *Container
+	assert.Equal(t, ErrNoDoc, nilCt.UnmarshalJSON([]byte(`1`)))
+	out, _ = nilCt.MarshalJSON()
+	assert.Equal(t, "null", string(out))
+}
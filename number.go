@@ -0,0 +1,176 @@
+package rapidjson
+
+// #include "rjwrapper.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"runtime"
+	"strconv"
+)
+
+var (
+	ErrNotNumber = errors.New("Not a number")
+
+	numberKinds = []string{
+		"none",
+		"int32",
+		"uint32",
+		"int64",
+		"uint64",
+		"double",
+	}
+)
+
+// NumberKind is the narrowest type that holds a number exactly, as told by
+// rapidjson's IsInt, IsUint, IsInt64 and IsUint64
+type NumberKind int
+
+const (
+	NumberKindNone NumberKind = iota // not a number
+	NumberKindInt32
+	NumberKindUint32
+	NumberKindInt64
+	NumberKindUint64
+	NumberKindDouble
+)
+
+func (kind NumberKind) String() string {
+	if kind < 0 || int(kind) >= len(numberKinds) {
+		return fmt.Sprintf("Unknown number kind %d", int(kind))
+	}
+	return numberKinds[kind]
+}
+
+// NumberKind inspects a number in a single call. Kinds are ordered, so a
+// number fits an int64 when its kind is at most NumberKindInt64
+func (ct *Container) NumberKind() NumberKind {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
+		return NumberKindNone
+	}
+	return NumberKind(C.GetNumberKind(ct.ct))
+}
+
+// IsLosslessDouble tells whether GetFloat, or converting the integer to
+// float64, keeps the exact value. Numbers with kept text are compared as
+// decimals, so 0.1 isn't lossless while 0.5 is
+func (ct *Container) IsLosslessDouble() bool {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
+		return false
+	} else if text, ok := ct.rawNumber(); ok {
+		r, ok := new(big.Rat).SetString(text)
+		if !ok {
+			return false
+		}
+		_, exact := r.Float64()
+		return exact
+	} else {
+		return CBoolTest(C.IsLosslessDouble(ct.ct))
+	}
+}
+
+// GetNumberString returns the text of a number. Numbers parsed with
+// ParseOptions.LosslessNumbers or set with SetNumberString keep their
+// original text, others are formatted like String()
+func (ct *Container) GetNumberString() (string, error) {
+	if ct == nil {
+		return "", ErrPathNotFound
+	} else if ct.stale() {
+		return "", ErrStaleContainer
+	} else if text, ok := ct.rawNumber(); ok {
+		return text, nil
+	} else if ct.GetType() == TypeNumber {
+		return ct.String(), nil
+	} else {
+		return "", ErrNotNumber
+	}
+}
+
+// GetBigInt returns an integer of any size. Numbers with a fraction or an
+// exponent return ErrNotInt, so do integers beyond uint64 that weren't kept
+// losslessly, as their digits were rounded when parsed
+func (ct *Container) GetBigInt() (*big.Int, error) {
+	if ct == nil {
+		return nil, ErrPathNotFound
+	} else if ct.stale() {
+		return nil, ErrStaleContainer
+	} else if text, ok := ct.rawNumber(); ok {
+		n, ok := new(big.Int).SetString(text, 10)
+		if !ok {
+			return nil, ErrNotInt
+		}
+		return n, nil
+	} else if n, err := ct.GetInt64(); err == nil {
+		return big.NewInt(n), nil
+	} else if n, err := ct.GetUint64(); err == nil {
+		return new(big.Int).SetUint64(n), nil
+	} else if ct.GetType() == TypeNumber {
+		return nil, ErrNotInt
+	} else {
+		return nil, ErrNotNumber
+	}
+}
+
+// GetBigFloat returns a number with enough precision to hold every digit
+// of its text. NaN and Infinity return ErrNotFloat
+func (ct *Container) GetBigFloat() (*big.Float, error) {
+	text, err := ct.GetNumberString()
+	if err != nil {
+		return nil, err
+	}
+	// 4 bits per digit is more than log2(10)
+	prec := uint(len(text)) * 4
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, err := big.ParseFloat(text, 10, prec, big.ToNearestEven)
+	if err != nil || f.IsInf() {
+		return nil, ErrNotFloat
+	}
+	return f, nil
+}
+
+// SetNumberString sets a number from its text, which is kept and written
+// back as is
+func (ct *Container) SetNumberString(text string) error {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
+	}
+	if !isNumber(text) {
+		return fmt.Errorf("%w: invalid number %q", ErrBadType, text)
+	}
+	cStr, size := stringToC(text)
+	C.SetRawNumber(ct.doc.json, ct.ct, cStr, size)
+	changed(ct)
+	return nil
+}
+
+func (ct *Container) rawNumber() (string, bool) {
+	defer runtime.KeepAlive(ct)
+	var size C.size_t
+	cStr := C.ValGetRawNumber(ct.ct, &size)
+	if cStr == nil {
+		return "", false
+	}
+	return stringFromC(cStr, size), true
+}
+
+// rawNumberBits converts lossless number text to a ValEncode number, which
+// is exact for integers that fit 64 bits
+func rawNumberBits(text string) (byte, uint64) {
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return C.JsonTagInt64, uint64(n)
+	} else if n, err := strconv.ParseUint(text, 10, 64); err == nil {
+		return C.JsonTagUint64, n
+	}
+	f, _ := strconv.ParseFloat(text, 64)
+	return C.JsonTagDouble, math.Float64bits(f)
+}
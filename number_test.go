@@ -0,0 +1,179 @@
+package rapidjson
+
+import (
+	encjson "encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+const testNumbers = `{"id": 123456789012345678901234567890, "price": 0.10000000000000000000001, "small": -7, "exp": 1.50E+2, "name": "x"}`
+
+func TestLosslessNumbers(t *testing.T) {
+	json, err := NewParsedStringJsonWithOptions(testNumbers, ParseOptions{LosslessNumbers: true})
+	assert.Nil(t, err, "should not error on lossless parsing")
+	defer json.Free()
+
+	// written back as is
+	assert.Equal(t, `{"id":123456789012345678901234567890,"price":0.10000000000000000000001,"small":-7,"exp":1.50E+2,"name":"x"}`, json.String())
+	out, err := json.Format(WriteOptions{Pretty: true, Indent: " "})
+	assert.Nil(t, err, "should not error on pretty output")
+	assert.Equal(t, "{\n \"id\": 123456789012345678901234567890,\n \"price\": 0.10000000000000000000001,\n \"small\": -7,\n \"exp\": 1.50E+2,\n \"name\": \"x\"\n}", out)
+
+	ct := json.GetContainer()
+	text, err := ct.GetMemberOrNil("id").GetNumberString()
+	assert.Nil(t, err, "should not error on number string")
+	assert.Equal(t, "123456789012345678901234567890", text)
+	id, err := ct.GetMemberOrNil("id").GetBigInt()
+	assert.Nil(t, err, "should not error on big int")
+	expected, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, 0, expected.Cmp(id))
+	price, err := ct.GetMemberOrNil("price").GetBigFloat()
+	assert.Nil(t, err, "should not error on big float")
+	assert.Equal(t, "0.10000000000000000000001", price.Text('g', -1))
+	_, err = ct.GetMemberOrNil("exp").GetBigInt()
+	assert.Equal(t, ErrNotInt, err)
+	_, err = ct.GetMemberOrNil("name").GetNumberString()
+	assert.Equal(t, ErrNotNumber, err)
+
+	// still numbers to the rest of the API
+	assert.Equal(t, TypeNumber, ct.GetMemberOrNil("small").GetType())
+	small, err := ct.GetMemberOrNil("small").GetInt()
+	assert.Nil(t, err, "should not error on small")
+	assert.Equal(t, -7, small)
+	exp, err := ct.GetMemberOrNil("exp").GetFloat()
+	assert.Nil(t, err, "should not error on exp")
+	assert.Equal(t, 150.0, exp)
+	_, err = ct.GetMemberOrNil("small").GetMemberCount()
+	assert.Equal(t, ErrNotObject, err)
+
+	v, err := ct.ToInterfaceWithOptions(ConvertOptions{Numbers: NumberJSONNumber})
+	assert.Nil(t, err, "should not error on json.Number conversion")
+	assert.Equal(t, encjson.Number("1.50E+2"), v.(map[string]interface{})["exp"])
+	v, _ = ct.ToInterface()
+	assert.Equal(t, int64(-7), v.(map[string]interface{})["small"])
+
+	// copies keep the text
+	copied := ct.GetCopy()
+	defer copied.doc.Free()
+	assert.Equal(t, json.String(), copied.String())
+	assert.True(t, copied.IsEqual(ct), "should equal copy")
+}
+
+func TestLosslessNumberSchema(t *testing.T) {
+	schema, err := NewParsedStringSchema(`{"properties": {"small": {"type": "integer", "maximum": 0}}}`)
+	assert.Nil(t, err, "should not error on schema")
+	defer schema.Free()
+
+	json := NewDoc()
+	defer json.Free()
+	assert.Nil(t, json.ParseStringValidatedWithOptions(testNumbers, schema, ParseOptions{LosslessNumbers: true}))
+	assert.Nil(t, schema.Validate(json.GetContainer()))
+
+	err = json.ParseStringValidatedWithOptions(`{"small": 1}`, schema, ParseOptions{LosslessNumbers: true})
+	assert.True(t, errors.Is(err, ErrSchemaInvalid), "should validate lossless numbers as numbers")
+}
+
+func TestSetNumberString(t *testing.T) {
+	json := NewDoc()
+	defer json.Free()
+	ct := json.GetContainerNewObj()
+	assert.Nil(t, ct.AddValue("n", nil))
+	assert.Nil(t, ct.GetMemberOrNil("n").SetNumberString("1.000"))
+	assert.Nil(t, ct.AddValue("big", new(big.Int).Lsh(big.NewInt(1), 100)))
+	assert.Equal(t, `{"n":1.000,"big":1267650600228229401496703205376}`, json.String())
+	assert.True(t, errors.Is(ct.GetMemberOrNil("n").SetNumberString("1."), ErrBadType), "should error on invalid number")
+
+	// NaN is only written when allowed
+	json2, err := NewParsedStringJsonWithOptions(`[NaN]`, ParseOptions{LosslessNumbers: true, NanAndInf: true})
+	assert.Nil(t, err, "should not error on NaN")
+	defer json2.Free()
+	_, err = json2.Format(WriteOptions{})
+	assert.Equal(t, ErrNanOrInf, err)
+	out, _ := json2.Format(WriteOptions{NanAndInf: true})
+	assert.Equal(t, `[NaN]`, out)
+	json3, err := NewParsedStringJsonWithOptions(`[-NaN, -Infinity]`, ParseOptions{LosslessNumbers: true, NanAndInf: true})
+	assert.Nil(t, err, "should not error on -NaN")
+	defer json3.Free()
+	_, err = json3.Format(WriteOptions{})
+	assert.Equal(t, ErrNanOrInf, err)
+	assert.Equal(t, "", json3.String())
+}
+
+func TestUnmarshalLossless(t *testing.T) {
+	var values struct {
+		ID    *big.Int       `json:"id"`
+		Price encjson.Number `json:"price"`
+		Small int            `json:"small"`
+	}
+	err := UnmarshalWithOptions([]byte(testNumbers), &values, ParseOptions{LosslessNumbers: true})
+	assert.Nil(t, err, "should not error on lossless unmarshal")
+	assert.Equal(t, "123456789012345678901234567890", values.ID.String())
+	assert.Equal(t, encjson.Number("0.10000000000000000000001"), values.Price)
+	assert.Equal(t, -7, values.Small)
+
+	out, err := Marshal(values.ID)
+	assert.Nil(t, err, "should not error on marshal")
+	assert.Equal(t, "123456789012345678901234567890", string(out))
+}
+
+func TestNumberKind(t *testing.T) {
+	json, err := NewParsedStringJson(`[-1, 3000000000, -3000000000, 18446744073709551615, 1.5, 9007199254740993, "1"]`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	items := json.GetContainer().GetArrayOrNil()
+	kinds := make([]NumberKind, len(items))
+	for i, item := range items {
+		kinds[i] = item.NumberKind()
+	}
+	assert.Equal(t, []NumberKind{NumberKindInt32, NumberKindUint32, NumberKindInt64, NumberKindUint64, NumberKindDouble, NumberKindInt64, NumberKindNone}, kinds)
+	assert.Equal(t, "uint64", NumberKindUint64.String())
+	assert.Equal(t, NumberKindNone, (*Container)(nil).NumberKind())
+
+	assert.True(t, items[0].IsLosslessDouble(), "small ints are lossless")
+	assert.True(t, items[4].IsLosslessDouble(), "doubles are lossless")
+	assert.False(t, items[5].IsLosslessDouble(), "2^53+1 is not lossless")
+	assert.False(t, items[6].IsLosslessDouble(), "strings are not numbers")
+
+	lossless, err := NewParsedStringJsonWithOptions(`[0.5, 0.1, 1.50E+2, 123456789012345678901234567890]`, ParseOptions{LosslessNumbers: true})
+	assert.Nil(t, err, "should not error on lossless parsing")
+	defer lossless.Free()
+	items = lossless.GetContainer().GetArrayOrNil()
+	assert.Equal(t, NumberKindDouble, items[0].NumberKind())
+	assert.Equal(t, NumberKindDouble, items[3].NumberKind())
+	assert.True(t, items[0].IsLosslessDouble(), "0.5 is exact in binary")
+	assert.False(t, items[1].IsLosslessDouble(), "0.1 is not exact in binary")
+	assert.True(t, items[2].IsLosslessDouble(), "150 is exact")
+	assert.False(t, items[3].IsLosslessDouble(), "30 digits don't fit a double")
+}
+
+func TestLosslessNumbersInRapidjson(t *testing.T) {
+	lossless, err := NewParsedStringJsonWithOptions(`{"type": "integer", "minimum": 10, "n": [5, 1.50]}`, ParseOptions{LosslessNumbers: true})
+	assert.Nil(t, err, "should not error on parsing")
+	defer lossless.Free()
+
+	// schemas compile the parsed numbers
+	schema, err := NewSchema(lossless)
+	assert.Nil(t, err, "should not error on schema")
+	defer schema.Free()
+	small, _ := NewParsedStringJson(`3`)
+	defer small.Free()
+	assert.NotNil(t, schema.Validate(small.GetContainer()), "should fail minimum")
+	large, _ := NewParsedStringJson(`12`)
+	defer large.Free()
+	assert.Nil(t, schema.Validate(large.GetContainer()))
+
+	// numbers equal plain numbers, not the object holding their text
+	plain, _ := NewParsedStringJson(`[5, {"number": "5"}, 1.5]`)
+	defer plain.Free()
+	n := lossless.GetContainer().GetMemberOrNil("n")
+	assert.True(t, n.GetArrayValue(0).IsEqual(plain.GetContainer().GetArrayValue(0)))
+	assert.False(t, n.GetArrayValue(0).IsEqual(plain.GetContainer().GetArrayValue(1)))
+	assert.True(t, n.GetArrayValue(1).IsEqual(plain.GetContainer().GetArrayValue(2)))
+	copied := n.GetCopy()
+	defer copied.GetDoc().Free()
+	assert.True(t, n.IsEqual(copied))
+}
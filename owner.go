@@ -0,0 +1,26 @@
+package rapidjson
+
+// #include "rjwrapper.h"
+import "C"
+
+// Ownership. A Doc owns every value made with its allocator, a value moved
+// into another Doc would be freed with its old Doc, so moves between Docs
+// are copies. The item is still left null, as after a move in the same Doc
+
+// movable returns item to be moved into ct, or for an item of another Doc a
+// copy made with ct's allocator, nulling item as a move would
+func (ct *Container) movable(item *Container) *Container {
+	if item.doc == ct.doc {
+		return item
+	}
+	m := ct.copyOf(item)
+	C.SetNull(item.ct)
+	return m
+}
+
+// copyOf returns a new Container of ct's Doc holding a copy of item
+func (ct *Container) copyOf(item *Container) *Container {
+	m := ct.doc.NewContainer()
+	C.CopyFrom(ct.doc.json, m.ct, item.ct)
+	return m
+}
@@ -0,0 +1,57 @@
+package rapidjson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func testOwnerDocs(t *testing.T) (*Doc, *Doc) {
+	opts := DocOptions{Allocator: AllocatorPool}
+	src, dst := NewDocWithOptions(opts), NewDocWithOptions(opts)
+	assert.Nil(t, src.ParseString(`{"a": {"s": "a long string value"}, "b": [1, "two"], "c": "c string", "d": {"e": "e string"}}`))
+	assert.Nil(t, dst.ParseString(`{"x": {"y": "y string"}, "list": []}`))
+	return src, dst
+}
+
+func TestMoveAcrossDocs(t *testing.T) {
+	src, dst := testOwnerDocs(t)
+	defer dst.Free()
+	from, to := src.GetContainer(), dst.GetContainer()
+
+	a := from.GetMemberOrNil("a")
+	assert.Nil(t, to.AddMember("a", a))
+	assert.Equal(t, TypeNull, a.GetType(), "moved items are left null")
+	assert.Nil(t, to.GetMemberOrNil("list").ArrayAppendContainer(from.GetMemberOrNil("b")))
+	to.GetMemberOrNil("x").SetContainer(from.GetMemberOrNil("c"))
+	p, _ := NewPointer("/new/0")
+	assert.Nil(t, to.SetPointer(p, from.GetMemberOrNil("d")))
+	assert.Equal(t, `{"a":null,"b":null,"c":null,"d":null}`, from.String())
+
+	// the copies outlive the source Doc
+	src.Free()
+	assert.Equal(t, `{"x":"c string","list":[[1,"two"]],"a":{"s":"a long string value"},"new":[{"e":"e string"}]}`, to.String())
+}
+
+func TestSwapAcrossDocs(t *testing.T) {
+	src, dst := testOwnerDocs(t)
+	from, to := src.GetContainer(), dst.GetContainer()
+
+	from.GetMemberOrNil("a").SwapContainer(to.GetMemberOrNil("x"))
+	p, _ := NewPointer("/list")
+	assert.Nil(t, to.SwapPointer(p, from.GetMemberOrNil("c")))
+	p, _ = NewPointer("/new")
+	assert.Nil(t, to.SwapPointer(p, from.GetMemberOrNil("d")))
+	fromStr := from.String()
+	dst.Free()
+	assert.Equal(t, `{"a":{"y":"y string"},"b":[1,"two"],"c":[],"d":null}`, fromStr)
+	assert.Equal(t, fromStr, from.String())
+
+	src2, dst2 := testOwnerDocs(t)
+	from, to = src2.GetContainer(), dst2.GetContainer()
+	to.GetMemberOrNil("x").SwapContainer(from.GetMemberOrNil("a"))
+	src2.Free()
+	assert.Equal(t, `{"x":{"s":"a long string value"},"list":[]}`, to.String())
+	dst2.Free()
+	src.Free()
+}
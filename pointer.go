@@ -0,0 +1,174 @@
+package rapidjson
+
+// #include <stdlib.h>
+// #include "rjwrapper.h"
+import "C"
+import "unsafe"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+var (
+	ErrBadPointer = errors.New("Invalid JSON pointer")
+
+	pointerErrors = []string{
+		"No error",
+		"A token must begin with a '/'",
+		"Invalid escape",
+		"Invalid percent encoding in URI fragment",
+		"A character must be percent encoded in URI fragment",
+	}
+)
+
+// Pointer is an RFC 6901 JSON Pointer, such as /a/0/b or its URI fragment
+// form #/a/0/b
+type Pointer struct {
+	path string
+}
+
+func NewPointer(path string) (Pointer, error) {
+	str, err := normalizePointer(path, false)
+	if err != nil {
+		return Pointer{}, err
+	}
+	return Pointer{path: str}, nil
+}
+func NewPointerFromTokens(tokens ...string) Pointer {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(EscapePointerToken(token))
+	}
+	return Pointer{path: sb.String()}
+}
+func EscapePointerToken(token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	return strings.ReplaceAll(token, "/", "~1")
+}
+func (p Pointer) String() string {
+	return p.path
+}
+func (p Pointer) URIFragment() string {
+	str, _ := normalizePointer(p.path, true)
+	return str
+}
+
+// MarshalText and UnmarshalText let Pointers be used as strings and map
+// keys by encoding/json and other text encoders
+func (p Pointer) MarshalText() ([]byte, error) {
+	return []byte(p.path), nil
+}
+func (p *Pointer) UnmarshalText(text []byte) error {
+	parsed, err := NewPointer(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+func normalizePointer(path string, uriFragment bool) (string, error) {
+	var size, errOffset C.size_t
+	var errCode C.int
+	cPath, cSize := stringToC(path)
+	cStr := C.PointerNormalize(cPath, cSize, BoolToC(uriFragment), &size, &errCode, &errOffset)
+	if cStr == nil {
+		return "", fmt.Errorf("%w: %s at offset %d", ErrBadPointer, pointerErrors[errCode], errOffset)
+	}
+	defer C.free(unsafe.Pointer(cStr))
+	return stringFromC(cStr, size), nil
+}
+
+// pointer getters/setters
+func (ct *Container) GetPointer(p Pointer) (*Container, error) {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return nil, ErrPathNotFound
+	} else if ct.stale() {
+		return nil, ErrStaleContainer
+	}
+	cPath, size := stringToC(p.path)
+	val := C.PointerGet(ct.ct, cPath, size)
+	if val == nil {
+		return nil, ErrPathNotFound
+	}
+	return ct.deriveDeep(val), nil
+}
+func (ct *Container) CreatePointer(p Pointer) (*Container, error) {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return nil, ErrPathNotFound
+	} else if ct.stale() {
+		return nil, ErrStaleContainer
+	}
+	cPath, size := stringToC(p.path)
+	val := C.PointerCreate(ct.doc.json, ct.ct, cPath, size)
+	// values may have been added on the way
+	changed(ct)
+	return ct.deriveDeep(val), nil
+}
+func (ct *Container) SetPointer(p Pointer, item *Container) error {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
+	if ct == nil || item == nil {
+		return ErrPathNotFound
+	} else if ct.stale() || item.stale() {
+		return ErrStaleContainer
+	}
+	cPath, size := stringToC(p.path)
+	C.PointerSet(ct.doc.json, ct.ct, cPath, size, ct.movable(item).ct)
+	changed(ct, item)
+	return nil
+}
+func (ct *Container) SetPointerValue(p Pointer, v interface{}) error {
+	if ct == nil {
+		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
+	}
+	item := ct.doc.NewContainer()
+	err := item.SetValue(v)
+	if err != nil {
+		return err
+	}
+	return ct.SetPointer(p, item)
+}
+func (ct *Container) SwapPointer(p Pointer, item *Container) error {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
+	if ct == nil || item == nil {
+		return ErrPathNotFound
+	} else if ct.stale() || item.stale() {
+		return ErrStaleContainer
+	}
+	if ct.doc != item.doc {
+		target, err := ct.CreatePointer(p)
+		if err != nil {
+			return err
+		}
+		target.SwapContainer(item)
+		return nil
+	}
+	cPath, size := stringToC(p.path)
+	C.PointerSwap(ct.doc.json, ct.ct, cPath, size, item.ct)
+	changed(ct, item)
+	return nil
+}
+func (ct *Container) ErasePointer(p Pointer) error {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
+	}
+	cPath, size := stringToC(p.path)
+	if !CBoolTest(C.PointerErase(ct.ct, cPath, size)) {
+		return ErrPathNotFound
+	}
+	changed(ct)
+	return nil
+}
@@ -0,0 +1,122 @@
+package rapidjson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func TestPointer(t *testing.T) {
+	p, err := NewPointer("/a/0/b")
+	assert.Nil(t, err, "should not error on pointer")
+	assert.Equal(t, "/a/0/b", p.String())
+	assert.Equal(t, "#/a/0/b", p.URIFragment())
+
+	p, err = NewPointer("#/a%20b/c~1d")
+	assert.Nil(t, err, "should not error on URI fragment pointer")
+	assert.Equal(t, "/a b/c~1d", p.String())
+
+	p = NewPointerFromTokens("a.b", "c/d", "e~f", "0")
+	assert.Equal(t, "/a.b/c~1d/e~0f/0", p.String())
+	assert.Equal(t, "e~0f", EscapePointerToken("e~f"))
+
+	_, err = NewPointer("a/b")
+	assert.True(t, errors.Is(err, ErrBadPointer), "should error on missing solidus")
+	_, err = NewPointer("/a~2")
+	assert.True(t, errors.Is(err, ErrBadPointer), "should error on bad escape")
+}
+
+func TestGetPointer(t *testing.T) {
+	json, err := NewParsedStringJson(`{"a":[{"b":1},{"b":2}],"c.d":{"e/f":true,"g~h":"i"}}`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	p, _ := NewPointer("/a/1/b")
+	b, err := ct.GetPointer(p)
+	assert.Nil(t, err, "should not error on array element")
+	assert.Equal(t, "2", b.String())
+
+	ef, err := ct.GetPointer(NewPointerFromTokens("c.d", "e/f"))
+	assert.Nil(t, err, "should not error on escaped key")
+	assert.Equal(t, "true", ef.String())
+
+	p, _ = NewPointer("#/c.d/g~0h")
+	gh, err := ct.GetPointer(p)
+	assert.Nil(t, err, "should not error on URI fragment")
+	assert.Equal(t, `"i"`, gh.String())
+
+	p, _ = NewPointer("")
+	root, err := ct.GetPointer(p)
+	assert.Nil(t, err, "should not error on root")
+	assert.True(t, root.IsEqual(ct))
+
+	p, _ = NewPointer("/a/2/b")
+	_, err = ct.GetPointer(p)
+	assert.Equal(t, ErrPathNotFound, err)
+}
+
+func TestSetPointer(t *testing.T) {
+	json, err := NewParsedStringJson(`{"a":[{"b":1},{"b":2}]}`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	p, _ := NewPointer("/a/0/b")
+	err = ct.SetPointerValue(p, "one")
+	assert.Nil(t, err, "should not error on set")
+
+	p, _ = NewPointer("/a/-")
+	err = ct.SetPointerValue(p, 3)
+	assert.Nil(t, err, "should not error on append")
+
+	p, _ = NewPointer("/x/y")
+	created, err := ct.CreatePointer(p)
+	assert.Nil(t, err, "should not error on create")
+	assert.Equal(t, TypeNull, created.GetType())
+	assert.Nil(t, created.SetValue(false))
+	assert.Equal(t, `{"a":[{"b":"one"},{"b":2},3],"x":{"y":false}}`, json.String())
+
+	item := json.NewContainer()
+	item.SetValue("swapped")
+	p, _ = NewPointer("/a/1/b")
+	err = ct.SwapPointer(p, item)
+	assert.Nil(t, err, "should not error on swap")
+	assert.Equal(t, "2", item.String())
+
+	p, _ = NewPointer("/a/0")
+	err = ct.ErasePointer(p)
+	assert.Nil(t, err, "should not error on erase")
+	p, _ = NewPointer("/a/5")
+	err = ct.ErasePointer(p)
+	assert.Equal(t, ErrPathNotFound, err)
+
+	assert.Equal(t, `{"a":[{"b":"swapped"},3],"x":{"y":false}}`, json.String())
+}
+
+func TestPointerLosslessNumbers(t *testing.T) {
+	json, err := NewParsedStringJsonWithOptions(`{"a": 1.50, "b": [2.50]}`, ParseOptions{LosslessNumbers: true})
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+
+	// lossless numbers are leaves
+	p, _ := NewPointer("/a")
+	a, err := ct.GetPointer(p)
+	assert.Nil(t, err, "should not error on get")
+	assert.Equal(t, "1.50", a.String())
+	p, _ = NewPointer("/a/number")
+	_, err = ct.GetPointer(p)
+	assert.Equal(t, ErrPathNotFound, err)
+	assert.Equal(t, ErrPathNotFound, ct.ErasePointer(p))
+
+	// and replaced like any other number
+	p, _ = NewPointer("/a/x")
+	assert.Nil(t, ct.SetPointerValue(p, 3))
+	p, _ = NewPointer("/b/0/0")
+	created, err := ct.CreatePointer(p)
+	assert.Nil(t, err, "should not error on create")
+	assert.Equal(t, TypeNull, created.GetType())
+	assert.Equal(t, `{"a":{"x":3},"b":[[null]]}`, json.String())
+}
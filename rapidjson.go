@@ -9,16 +9,21 @@ import "unsafe"
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
 	"sort"
 	"strings"
 )
 
 var (
 	ErrJsonParse    = errors.New("JSON parsing error")
+	ErrSerialize    = errors.New("JSON serialization error")
 	ErrNotArray     = errors.New("Not an array")
 	ErrNotObject    = errors.New("Not an object")
 	ErrPathNotFound = errors.New("Path not found")
 	ErrNotInt       = errors.New("Not an int")
+	ErrNotUint      = errors.New("Not an unsigned int")
 	ErrNotFloat     = errors.New("Not a float")
 	ErrNotBool      = errors.New("Not a bool")
 	ErrNotString    = errors.New("Not a string")
@@ -26,6 +31,10 @@ var (
 	ErrMemberExists = errors.New("Member already exists")
 	ErrOutOfBounds  = errors.New("Array index out of bounds")
 
+	ErrNanOrInf         = fmt.Errorf("%w: NaN or Inf needs WriteOptions.NanAndInf", ErrSerialize)
+	ErrBadIndent        = errors.New("Indent must repeat one of space, tab, CR or LF")
+	ErrBadDecimalPlaces = errors.New("Max decimal places must not be negative")
+
 	parseErrors = []string{
 		"No error",
 		"The document is empty",
@@ -48,6 +57,83 @@ var (
 	}
 )
 
+// ParseErrorCode mirrors rapidjson::ParseErrorCode
+type ParseErrorCode int
+
+const (
+	ParseErrorNone ParseErrorCode = iota
+	ParseErrorDocumentEmpty
+	ParseErrorDocumentRootNotSingular
+	ParseErrorValueInvalid
+	ParseErrorObjectMissName
+	ParseErrorObjectMissColon
+	ParseErrorObjectMissCommaOrCurlyBracket
+	ParseErrorArrayMissCommaOrSquareBracket
+	ParseErrorStringUnicodeEscapeInvalidHex
+	ParseErrorStringUnicodeSurrogateInvalid
+	ParseErrorStringEscapeInvalid
+	ParseErrorStringMissQuotationMark
+	ParseErrorStringInvalidEncoding
+	ParseErrorNumberTooBig
+	ParseErrorNumberMissFraction
+	ParseErrorNumberMissExponent
+	ParseErrorTermination
+	ParseErrorUnspecificSyntaxError
+)
+
+func (code ParseErrorCode) String() string {
+	if code < 0 || int(code) >= len(parseErrors) {
+		return fmt.Sprintf("Unknown parse error %d", int(code))
+	}
+	return parseErrors[code]
+}
+
+// number of bytes kept on each side of the error offset in ParseError.Context
+const parseErrorContext = 20
+
+// ParseError is returned by the parsing funcs, errors.Is(err, ErrJsonParse)
+// holds for every ParseError
+type ParseError struct {
+	Code    ParseErrorCode
+	Offset  int    // byte offset of the error in the input
+	Line    int    // 1-based line of Offset
+	Column  int    // 1-based byte column of Offset
+	Context string // input surrounding Offset
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s at line %d column %d (offset %d) near %q",
+		ErrJsonParse.Error(), e.Code, e.Line, e.Column, e.Offset, e.Context)
+}
+func (e *ParseError) Unwrap() error {
+	return ErrJsonParse
+}
+
+func newParseError(code ParseErrorCode, offset int, input string) *ParseError {
+	if offset > len(input) {
+		offset = len(input)
+	}
+	prefix := input[:offset]
+	line := strings.Count(prefix, "\n") + 1
+	column := offset - strings.LastIndexByte(prefix, '\n')
+
+	start := offset - parseErrorContext
+	if start < 0 {
+		start = 0
+	}
+	end := offset + parseErrorContext
+	if end > len(input) {
+		end = len(input)
+	}
+	return &ParseError{
+		Code:    code,
+		Offset:  offset,
+		Line:    line,
+		Column:  column,
+		Context: input[start:end],
+	}
+}
+
 const (
 	TypeNull   int = 0
 	TypeFalse  int = 1
@@ -58,6 +144,44 @@ const (
 	TypeNumber int = 6
 )
 
+// ParseOptions maps onto rapidjson parse flags, zero value is the default
+// strict parse
+type ParseOptions struct {
+	Comments         bool // allow // and /* */ comments
+	TrailingCommas   bool // allow a trailing comma in objects and arrays
+	NanAndInf        bool // allow NaN, Inf, Infinity, -Inf and -Infinity
+	FullPrecision    bool // parse numbers in full precision (slower)
+	ValidateEncoding bool // validate UTF-8 encoding of strings
+	Iterative        bool // constant stack size parsing for deeply nested input
+	LosslessNumbers  bool // keep the text of each number, see GetNumberString
+}
+
+func (opts ParseOptions) flags() C.unsigned {
+	var flags C.unsigned
+	if opts.Comments {
+		flags |= C.JsonParseComments
+	}
+	if opts.TrailingCommas {
+		flags |= C.JsonParseTrailingCommas
+	}
+	if opts.NanAndInf {
+		flags |= C.JsonParseNanAndInf
+	}
+	if opts.FullPrecision {
+		flags |= C.JsonParseFullPrecision
+	}
+	if opts.ValidateEncoding {
+		flags |= C.JsonParseValidateEncoding
+	}
+	if opts.Iterative {
+		flags |= C.JsonParseIterative
+	}
+	if opts.LosslessNumbers {
+		flags |= C.JsonParseNumbersAsStrings
+	}
+	return flags
+}
+
 type RJCommon interface {
 	Free()
 }
@@ -65,11 +189,24 @@ type RJCommon interface {
 type Doc struct {
 	json      C.JsonDoc
 	allocated []RJCommon
+	finalizer bool // see leak.go
+
+	// see stale.go
+	checkStale bool
+	clock      uint64
+	epoch      uint64
+	changes    map[C.JsonVal]uint64
 }
 
 type Container struct {
 	doc *Doc
 	ct  C.JsonVal
+
+	// see stale.go
+	parent *Container
+	stamp  uint64
+	epoch  uint64
+	deep   bool
 }
 
 // bool helpers
@@ -88,26 +225,65 @@ func BoolToC(b bool) C.int {
 	}
 }
 
+// string helpers, strings are passed with explicit lengths so embedded NUL
+// bytes survive. C must not keep the pointer after the call returns
+func stringToC(s string) (*C.char, C.size_t) {
+	return (*C.char)(unsafe.Pointer(unsafe.StringData(s))), C.size_t(len(s))
+}
+func bytesToC(b []byte) (*C.char, C.size_t) {
+	return (*C.char)(unsafe.Pointer(unsafe.SliceData(b))), C.size_t(len(b))
+}
+func stringFromC(cStr *C.char, size C.size_t) string {
+	return C.GoStringN(cStr, C.int(size))
+}
+
 // initialization
 func NewDoc() *Doc {
 	var json Doc
-	json.json = C.JsonInit()
+	json.init(DocOptions{}, true)
 	return &json
 }
+
+// Free releases the Doc and its Containers, freeing twice is a no-op
 func (json *Doc) Free() {
-	if json == nil {
+	defer runtime.KeepAlive(json)
+	if json == nil || json.json == nil {
 		return
 	}
+	if json.finalizer {
+		runtime.SetFinalizer(json, nil)
+		json.finalizer = false
+	}
 	for _, ct := range json.allocated {
 		ct.Free()
 	}
+	json.allocated = nil
 	C.JsonFree(json.json)
+	untrack(json.json)
+	json.json = nil
+}
+
+// Reset empties the Doc for reuse, like a new Doc with the same allocator.
+// Its Containers are freed, and AllocatorPool keeps the memory it grew to
+func (json *Doc) Reset() {
+	defer runtime.KeepAlive(json)
+	if json == nil || json.json == nil {
+		return
+	}
+	for _, ct := range json.allocated {
+		ct.Free()
+	}
+	json.allocated = nil
+	C.JsonReset(json.json)
+	json.newEpoch()
 }
 func (json *Doc) NewContainer() *Container {
+	defer runtime.KeepAlive(json)
 	var ct Container
 	ct.doc = json
 	ct.ct = C.ValInit()
-	json.allocated = append([]RJCommon{&ct}, json.allocated...)
+	ct.epoch = json.epoch
+	json.allocated = append([]RJCommon{allocatedVal{ct.ct}}, json.allocated...)
 	return &ct
 }
 func (json *Doc) NewContainerObj() *Container {
@@ -121,6 +297,7 @@ func (json *Doc) NewContainerArray() *Container {
 	return ct
 }
 func (ct *Container) Free() {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return
 	}
@@ -128,47 +305,88 @@ func (ct *Container) Free() {
 		C.ValFree(ct.ct)
 	}
 }
+
+// allocatedVal is a value of NewContainer for the Doc to free. It doesn't
+// point back at the Doc, a cycle would keep the finalizer from running
+type allocatedVal struct {
+	val C.JsonVal
+}
+
+func (val allocatedVal) Free() {
+	C.ValFree(val.val)
+}
+
 func (json *Doc) GetContainer() *Container {
+	defer runtime.KeepAlive(json)
 	var ct Container
 	ct.ct = C.JsonVal(unsafe.Pointer(json.json))
 	ct.doc = json
+	ct.epoch = json.epoch
 	return &ct
 }
 func (json *Doc) GetContainerNewObj() *Container {
-	var ct Container
-	ct.ct = C.JsonVal(unsafe.Pointer(json.json))
-	ct.doc = json
+	ct := json.GetContainer()
 	ct.InitObj()
-	return &ct
+	return ct
 }
 func (ct *Container) GetCopy() *Container {
-	if ct == nil {
+	if ct == nil || ct.stale() {
 		return nil
 	}
-	copyDoc := NewDoc()
+	copyDoc := NewDocWithOptions(DocOptions{CheckStale: ct.doc != nil && ct.doc.checkStale})
 	ctCopy := copyDoc.GetContainer()
 	ctCopy.SetContainerCopy(ct)
 	return ctCopy
 }
 
+// GetDoc returns the Doc ct belongs to, such as the new Doc of GetCopy
+func (ct *Container) GetDoc() *Doc {
+	if ct == nil {
+		return nil
+	}
+	return ct.doc
+}
+
 func (json *Doc) GetAllocated() int {
 	return len(json.allocated)
 }
 
 // parse
 func (json *Doc) Parse(input []byte) error {
-	return json.ParseString(string(input))
+	defer runtime.KeepAlive(json)
+	cStr, size := bytesToC(input)
+	C.JsonParse(json.json, cStr, size)
+
+	return json.parseResult(func() string { return string(input) })
 }
 func (json *Doc) ParseString(input string) error {
-	cStr := C.CString(input)
-	defer C.free(unsafe.Pointer(cStr))
-	C.JsonParse(json.json, cStr)
+	defer runtime.KeepAlive(json)
+	cStr, size := stringToC(input)
+	C.JsonParse(json.json, cStr, size)
+
+	return json.parseResult(func() string { return input })
+}
+func (json *Doc) ParseWithOptions(input []byte, opts ParseOptions) error {
+	defer runtime.KeepAlive(json)
+	cStr, size := bytesToC(input)
+	C.JsonParseFlags(json.json, cStr, size, opts.flags())
+
+	return json.parseResult(func() string { return string(input) })
+}
+func (json *Doc) ParseStringWithOptions(input string, opts ParseOptions) error {
+	defer runtime.KeepAlive(json)
+	cStr, size := stringToC(input)
+	C.JsonParseFlags(json.json, cStr, size, opts.flags())
 
+	return json.parseResult(func() string { return input })
+}
+func (json *Doc) parseResult(input func() string) error {
+	// the root was replaced
+	changed(json.GetContainer())
 	if json.HasParseError() {
-		errCode := int(C.GetParseErrorCode(json.json))
+		errCode := ParseErrorCode(C.GetParseErrorCode(json.json))
 		errOffset := int(C.GetParseErrorOffset(json.json))
-		errStr := "JSON parsing error: " + parseErrors[errCode] + " at: " + input[:errOffset]
-		return errors.New(errStr)
+		return newParseError(errCode, errOffset, input())
 	} else {
 		return nil
 	}
@@ -183,42 +401,65 @@ func NewParsedStringJson(input string) (*Doc, error) {
 	err := doc.ParseString(input)
 	return doc, err
 }
+func NewParsedJsonWithOptions(input []byte, opts ParseOptions) (*Doc, error) {
+	doc := NewDoc()
+	err := doc.ParseWithOptions(input, opts)
+	return doc, err
+}
+func NewParsedStringJsonWithOptions(input string, opts ParseOptions) (*Doc, error) {
+	doc := NewDoc()
+	err := doc.ParseStringWithOptions(input, opts)
+	return doc, err
+}
 func (json *Doc) HasParseError() bool {
+	defer runtime.KeepAlive(json)
 	return CBoolTest(C.HasParseError(json.json))
 }
 
-// get string/bytes output
+// get string/bytes output, empty if the value can't be written (NaN or Inf),
+// use Format for the error
 func (json *Doc) String() string {
-	cStr := C.GetString(json.json)
+	defer runtime.KeepAlive(json)
+	var size C.size_t
+	cStr := C.GetString(json.json, &size)
 	defer C.free(unsafe.Pointer(cStr))
-	str := C.GoString(cStr)
+	str := stringFromC(cStr, size)
 	return str
 }
 func (json *Doc) Pretty() string {
-	cStr := C.GetPrettyString(json.json)
+	defer runtime.KeepAlive(json)
+	var size C.size_t
+	cStr := C.GetPrettyString(json.json, &size)
 	defer C.free(unsafe.Pointer(cStr))
-	str := C.GoString(cStr)
+	str := stringFromC(cStr, size)
 	return str
 }
 func (json *Doc) Bytes() []byte {
-	return []byte(json.String())
+	defer runtime.KeepAlive(json)
+	var size C.size_t
+	cStr := C.GetString(json.json, &size)
+	defer C.free(unsafe.Pointer(cStr))
+	return C.GoBytes(unsafe.Pointer(cStr), C.int(size))
 }
 
 // various getters
 func (ct *Container) HasMember(key string) bool {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return false
 	} else if CBoolTest(C.IsObj(ct.ct)) {
-		cStr := C.CString(key)
-		defer C.free(unsafe.Pointer(cStr))
-		return CBoolTest(C.HasMember(ct.ct, cStr))
+		cStr, size := stringToC(key)
+		return CBoolTest(C.HasMember(ct.ct, cStr, size))
 	} else {
 		return false
 	}
 }
 func (ct *Container) GetMemberCount() (int, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return 0, ErrNotObject
+	} else if ct.stale() {
+		return 0, ErrStaleContainer
 	} else if CBoolTest(C.IsObj(ct.ct)) {
 		return int(C.GetMemberCount(ct.ct)), nil
 	} else {
@@ -226,12 +467,13 @@ func (ct *Container) GetMemberCount() (int, error) {
 	}
 }
 func (ct *Container) GetMemberName(index int) string {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return ""
 	}
-	cStr := C.GetMemberName(ct.ct, C.int(index))
-	defer C.free(unsafe.Pointer(cStr))
-	str := C.GoString(cStr)
+	var size C.size_t
+	cStr := C.GetMemberName(ct.ct, C.int(index), &size)
+	str := stringFromC(cStr, size)
 	return str
 }
 func (ct *Container) GetMemberNames() ([]string, error) {
@@ -265,17 +507,16 @@ func (ct *Container) GetMemberMap() (map[string]*Container, error) {
 }
 
 func (ct *Container) GetMember(key string) (*Container, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return nil, ErrPathNotFound
+	} else if ct.stale() {
+		return nil, ErrStaleContainer
 	}
-	cStr := C.CString(key)
-	defer C.free(unsafe.Pointer(cStr))
+	cStr, size := stringToC(key)
 	if CBoolTest(C.IsObj(ct.ct)) {
 		if ct.HasMember(key) {
-			var m Container
-			m.doc = ct.doc
-			m.ct = C.GetMember(ct.ct, cStr)
-			return &m, nil
+			return ct.derive(C.GetMember(ct.ct, cStr, size)), nil
 		} else {
 			return nil, ErrPathNotFound
 		}
@@ -284,28 +525,36 @@ func (ct *Container) GetMember(key string) (*Container, error) {
 	}
 }
 func (ct *Container) String() string {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return ""
 	}
-	cStr := C.ValGetString(ct.ct)
+	var size C.size_t
+	cStr := C.ValGetString(ct.ct, &size)
 	defer C.free(unsafe.Pointer(cStr))
-	str := C.GoString(cStr)
+	str := stringFromC(cStr, size)
 	return str
 }
 func (ct *Container) Pretty() string {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return ""
 	}
-	cStr := C.ValGetPrettyString(ct.ct)
+	var size C.size_t
+	cStr := C.ValGetPrettyString(ct.ct, &size)
 	defer C.free(unsafe.Pointer(cStr))
-	str := C.GoString(cStr)
+	str := stringFromC(cStr, size)
 	return str
 }
 func (ct *Container) Bytes() []byte {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return []byte("")
 	}
-	return []byte(ct.String())
+	var size C.size_t
+	cStr := C.ValGetString(ct.ct, &size)
+	defer C.free(unsafe.Pointer(cStr))
+	return C.GoBytes(unsafe.Pointer(cStr), C.int(size))
 }
 
 func (ct *Container) GetPathContainer(path string) (*Container, error) {
@@ -376,8 +625,12 @@ func (ct *Container) GetPathNewContainer(path string) (*Container, error) {
 	return prev, nil
 }
 func (ct *Container) IsEqual(other *Container) bool {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(other)
 	if ct == nil || other == nil {
 		return ct == other
+	} else if ct.stale() || other.stale() {
+		return false
 	} else {
 		res := C.IsValEqual(ct.ct, other.ct)
 		return CBoolTest(res)
@@ -386,16 +639,21 @@ func (ct *Container) IsEqual(other *Container) bool {
 
 // typed getters
 func (ct *Container) GetType() int {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return TypeNull
 	} else {
 		return int(C.GetType(ct.ct))
 	}
 }
 func (ct *Container) GetInt() (int, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		var result int
 		return result, ErrPathNotFound
+	} else if ct.stale() {
+		var result int
+		return result, ErrStaleContainer
 	} else if CBoolTest(C.IsInt(ct.ct)) {
 		result := int(C.ValGetInt(ct.ct))
 		return result, nil
@@ -405,9 +663,13 @@ func (ct *Container) GetInt() (int, error) {
 	}
 }
 func (ct *Container) GetInt64() (int64, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		var result int64
 		return result, ErrPathNotFound
+	} else if ct.stale() {
+		var result int64
+		return result, ErrStaleContainer
 	} else if CBoolTest(C.IsInt64(ct.ct)) {
 		result := int64(C.ValGetInt64(ct.ct))
 		return result, nil
@@ -416,10 +678,46 @@ func (ct *Container) GetInt64() (int64, error) {
 		return result, ErrNotInt
 	}
 }
+func (ct *Container) GetUint() (uint, error) {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		var result uint
+		return result, ErrPathNotFound
+	} else if ct.stale() {
+		var result uint
+		return result, ErrStaleContainer
+	} else if CBoolTest(C.IsUint(ct.ct)) {
+		result := uint(C.ValGetUint(ct.ct))
+		return result, nil
+	} else {
+		var result uint
+		return result, ErrNotUint
+	}
+}
+func (ct *Container) GetUint64() (uint64, error) {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		var result uint64
+		return result, ErrPathNotFound
+	} else if ct.stale() {
+		var result uint64
+		return result, ErrStaleContainer
+	} else if CBoolTest(C.IsUint64(ct.ct)) {
+		result := uint64(C.ValGetUint64(ct.ct))
+		return result, nil
+	} else {
+		var result uint64
+		return result, ErrNotUint
+	}
+}
 func (ct *Container) GetFloat() (float64, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		var result float64
 		return result, ErrPathNotFound
+	} else if ct.stale() {
+		var result float64
+		return result, ErrStaleContainer
 	} else if CBoolTest(C.IsDouble(ct.ct)) {
 		result := float64(C.ValGetDouble(ct.ct))
 		return result, nil
@@ -429,9 +727,13 @@ func (ct *Container) GetFloat() (float64, error) {
 	}
 }
 func (ct *Container) GetBool() (bool, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		var result bool
 		return result, ErrPathNotFound
+	} else if ct.stale() {
+		var result bool
+		return result, ErrStaleContainer
 	} else if CBoolTest(C.IsBool(ct.ct)) {
 		result := CBoolTest(C.ValGetBool(ct.ct))
 		return result, nil
@@ -441,13 +743,17 @@ func (ct *Container) GetBool() (bool, error) {
 	}
 }
 func (ct *Container) GetString() (string, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		var result string
 		return result, ErrPathNotFound
+	} else if ct.stale() {
+		var result string
+		return result, ErrStaleContainer
 	} else if CBoolTest(C.IsString(ct.ct)) {
-		cStr := C.ValGetBasicString(ct.ct)
-		defer C.free(unsafe.Pointer(cStr))
-		str := C.GoString(cStr)
+		var size C.size_t
+		cStr := C.ValGetBasicString(ct.ct, &size)
+		str := stringFromC(cStr, size)
 		return str, nil
 	} else {
 		var result string
@@ -455,16 +761,23 @@ func (ct *Container) GetString() (string, error) {
 	}
 }
 func (ct *Container) GetValue() (interface{}, error) {
+	if ct.stale() {
+		return nil, ErrStaleContainer
+	}
 	switch ct.GetType() {
 	case TypeString:
 		return ct.GetString()
 	case TypeTrue, TypeFalse:
 		return ct.GetBool()
 	case TypeNumber:
-		if r, err := ct.GetInt64(); err == ErrNotInt {
+		// integers above math.MaxInt64 stay exact as uint64
+		switch ct.NumberKind() {
+		case NumberKindInt32, NumberKindUint32, NumberKindInt64:
+			return ct.GetInt64()
+		case NumberKindUint64:
+			return ct.GetUint64()
+		default:
 			return ct.GetFloat()
-		} else {
-			return r, err
 		}
 	case TypeArray, TypeObject:
 		return nil, ErrBadType
@@ -473,8 +786,11 @@ func (ct *Container) GetValue() (interface{}, error) {
 	}
 }
 func (ct *Container) GetArraySize() (int, error) {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return 0, ErrPathNotFound
+	} else if ct.stale() {
+		return 0, ErrStaleContainer
 	} else if CBoolTest(C.IsArray(ct.ct)) {
 		size := int(C.ValArraySize(ct.ct))
 		return size, nil
@@ -483,13 +799,11 @@ func (ct *Container) GetArraySize() (int, error) {
 	}
 }
 func (ct *Container) GetArrayValue(index int) *Container {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return nil
 	}
-	var a Container
-	a.doc = ct.doc
-	a.ct = C.GetArrayValueAt(ct.ct, C.int(index))
-	return &a
+	return ct.derive(C.GetArrayValueAt(ct.ct, C.int(index)))
 }
 
 func (ct *Container) GetIntArray() ([]int64, error) {
@@ -548,9 +862,13 @@ func (ct *Container) GetArray() ([]*Container, int, error) {
 
 // setters
 func (ct *Container) SetValue(v interface{}) error {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	}
+	changed(ct)
 	if v == nil {
 		C.SetNull(ct.ct)
 		return nil
@@ -572,6 +890,15 @@ func (ct *Container) SetValue(v interface{}) error {
 	case int8:
 		C.SetInt(ct.ct, C.int(v.(int8)))
 		return nil
+	case uint64:
+		C.SetUint64(ct.ct, C.uint64_t(v.(uint64)))
+		return nil
+	case uint32:
+		C.SetUint64(ct.ct, C.uint64_t(v.(uint32)))
+		return nil
+	case uint:
+		C.SetUint64(ct.ct, C.uint64_t(v.(uint)))
+		return nil
 	case float64:
 		C.SetDouble(ct.ct, C.double(v.(float64)))
 		return nil
@@ -579,35 +906,51 @@ func (ct *Container) SetValue(v interface{}) error {
 		C.SetBool(ct.ct, BoolToC(v.(bool)))
 		return nil
 	case string:
-		cStr := C.CString(v.(string))
-		defer C.free(unsafe.Pointer(cStr))
-		C.SetString(ct.doc.json, ct.ct, cStr)
+		cStr, size := stringToC(v.(string))
+		C.SetString(ct.doc.json, ct.ct, cStr, size)
 		return nil
 	default:
-		return ErrBadType
+		return ct.setReflect(reflect.ValueOf(v), &setState{})
 	}
 }
+
+// SetContainer moves item into ct, leaving item null. Without an error to
+// return, it does nothing when either is stale
 func (ct *Container) SetContainer(item *Container) {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
+	if ct == nil || ct.stale() || item.stale() {
 		return
 	}
-	C.SetValue(ct.ct, item.ct)
+	C.SetValue(ct.ct, ct.movable(item).ct)
+	changed(ct, item)
 }
+
+// SetContainerCopy copies item into ct, doing nothing when either is stale
 func (ct *Container) SetContainerCopy(item *Container) {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
+	if ct == nil || ct.stale() || item.stale() {
 		return
 	}
 	C.CopyFrom(ct.doc.json, ct.ct, item.ct)
+	changed(ct)
 }
+
+// InitObj makes ct an empty object, doing nothing when it's stale
 func (ct *Container) InitObj() {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return
 	}
 	ct.ct = C.InitObj(ct.ct)
+	changed(ct)
 }
 func (ct *Container) AddValue(key string, v interface{}) error {
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	}
 	item := ct.doc.NewContainer()
 	err := item.SetValue(v)
@@ -618,52 +961,58 @@ func (ct *Container) AddValue(key string, v interface{}) error {
 	return ct.AddMember(key, item)
 }
 func (ct *Container) AddMember(key string, item *Container) error {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() || item.stale() {
+		return ErrStaleContainer
 	} else if !CBoolTest(C.IsObj(ct.ct)) {
 		return ErrNotObject
 	} else {
-		cStr := C.CString(key)
-		defer C.free(unsafe.Pointer(cStr))
-		if CBoolTest(C.HasMember(ct.ct, cStr)) {
+		cStr, size := stringToC(key)
+		if CBoolTest(C.HasMember(ct.ct, cStr, size)) {
 			return ErrMemberExists
 		} else {
-			C.AddStrMember(ct.doc.json, ct.ct, cStr, item.ct)
+			C.AddStrMember(ct.doc.json, ct.ct, cStr, size, ct.movable(item).ct)
+			changed(ct, item)
 			return nil
 		}
 	}
 }
 func (ct *Container) AddMemberCopy(key string, item *Container) error {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() || item.stale() {
+		return ErrStaleContainer
 	} else if !CBoolTest(C.IsObj(ct.ct)) {
 		return ErrNotObject
 	} else {
-		ct.SetMemberValue(key, nil)
-		target, err := ct.GetMember(key)
-		if err != nil {
-			return err
-		}
-		target.SetContainerCopy(item)
-		return nil
+		// copied first, setting the member may make item stale
+		return ct.SetMember(key, ct.copyOf(item))
 	}
 }
 func (ct *Container) AddMemberArray(key string, items []*Container) error {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	} else if !CBoolTest(C.IsObj(ct.ct)) {
 		return ErrNotObject
 	} else {
-		cStr := C.CString(key)
-		defer C.free(unsafe.Pointer(cStr))
-		if CBoolTest(C.HasMember(ct.ct, cStr)) {
+		cStr, size := stringToC(key)
+		if CBoolTest(C.HasMember(ct.ct, cStr, size)) {
 			return ErrMemberExists
 		} else {
 			array := ct.doc.NewContainerArray()
 			for _, item := range items {
 				array.ArrayAppendContainer(item)
 			}
-			C.AddStrMember(ct.doc.json, ct.ct, cStr, array.ct)
+			C.AddStrMember(ct.doc.json, ct.ct, cStr, size, array.ct)
+			changed(ct, array)
 			return nil
 		}
 
@@ -672,20 +1021,24 @@ func (ct *Container) AddMemberArray(key string, items []*Container) error {
 func (ct *Container) SetMember(key string, item *Container) error {
 	if ct == nil {
 		return ErrPathNotFound
+	} else if item.stale() {
+		return ErrStaleContainer
 	}
 	target, err := ct.GetMember(key)
 	if err == nil {
 		target.SetContainer(item)
-	} else if err == ErrNotObject {
-		return err
 	} else if err == ErrPathNotFound {
-		ct.AddMember(key, item)
+		return ct.AddMember(key, item)
+	} else {
+		return err
 	}
 	return nil
 }
 func (ct *Container) SetMemberValue(key string, v interface{}) error {
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	}
 	item := ct.doc.NewContainer()
 	err := item.SetValue(v)
@@ -696,22 +1049,20 @@ func (ct *Container) SetMemberValue(key string, v interface{}) error {
 	return ct.SetMember(key, item)
 }
 func (ct *Container) SetMemberCopy(key string, item *Container) error {
+	defer runtime.KeepAlive(item)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() || item.stale() {
+		return ErrStaleContainer
 	}
-	target, err := ct.GetMember(key)
-	if err == nil {
-		target.SetContainerCopy(item)
-	} else if err == ErrNotObject {
-		return err
-	} else if err == ErrPathNotFound {
-		ct.AddMemberCopy(key, item)
-	}
-	return nil
+	// copied first, replacing the member may make item stale
+	return ct.SetMember(key, ct.copyOf(item))
 }
 func (ct *Container) AddMemberAtPath(path string, item *Container) error {
 	if ct == nil {
 		return ErrPathNotFound
+	} else if item.stale() {
+		return ErrStaleContainer
 	}
 	dest, err := ct.GetPathNewContainer(path)
 	if err != nil {
@@ -729,34 +1080,50 @@ func (ct *Container) AddValueAtPath(path string, v interface{}) error {
 		return err
 	}
 	item := ct.doc.NewContainer()
-	item.SetValue(v)
+	err = item.SetValue(v)
+	if err != nil {
+		return err
+	}
 	dest.SetContainer(item)
 	return nil
 }
 
+// InitArray makes ct an empty array, doing nothing when it's stale
 func (ct *Container) InitArray() {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return
 	}
 	C.InitArray(ct.ct)
+	changed(ct)
 }
 func (ct *Container) ArrayAppendContainer(item *Container) error {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() || item.stale() {
+		return ErrStaleContainer
 	} else if CBoolTest(C.IsArray(ct.ct)) {
-		C.ArrayAppend(ct.doc.json, ct.ct, item.ct)
+		C.ArrayAppend(ct.doc.json, ct.ct, ct.movable(item).ct)
+		changed(ct, item)
 		return nil
 	} else {
 		return ErrNotArray
 	}
 }
 func (ct *Container) ArrayAppendCopy(item *Container) error {
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
 	if ct == nil || item == nil {
 		return ErrPathNotFound
+	} else if ct.stale() || item.stale() {
+		return ErrStaleContainer
 	} else if CBoolTest(C.IsArray(ct.ct)) {
 		newCt := ct.doc.NewContainer()
 		newCt.SetContainerCopy(item)
 		C.ArrayAppend(ct.doc.json, ct.ct, newCt.ct)
+		changed(ct, newCt)
 		return nil
 	} else {
 		return ErrNotArray
@@ -765,47 +1132,78 @@ func (ct *Container) ArrayAppendCopy(item *Container) error {
 func (ct *Container) ArrayAppend(v interface{}) error {
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	}
 	item := ct.doc.NewContainer()
-	item.SetValue(v)
+	err := item.SetValue(v)
+	if err != nil {
+		return err
+	}
 	return ct.ArrayAppendContainer(item)
 }
+
+// SwapContainer exchanges the values of ct and item, doing nothing when
+// either is stale
 func (ct *Container) SwapContainer(item *Container) {
-	C.Swap(ct.ct, item.ct)
+	defer runtime.KeepAlive(ct)
+	defer runtime.KeepAlive(item)
+	if ct.stale() || item.stale() {
+		return
+	} else if ct.doc != item.doc {
+		// each side takes a copy made with its own allocator
+		mine, theirs := ct.copyOf(item), item.copyOf(ct)
+		C.SetValue(ct.ct, mine.ct)
+		C.SetValue(item.ct, theirs.ct)
+	} else {
+		C.Swap(ct.ct, item.ct)
+	}
+	changed(ct, item)
 }
 
 // deleters
 func (ct *Container) RemoveMember(key string) error {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	} else if !CBoolTest(C.IsObj(ct.ct)) {
 		return ErrNotObject
 	} else {
-		cStr := C.CString(key)
-		defer C.free(unsafe.Pointer(cStr))
-		C.RemoveMember(ct.ct, cStr)
+		cStr, size := stringToC(key)
+		C.RemoveMember(ct.ct, cStr, size)
+		changed(ct)
 	}
 	return nil
 }
 func (ct *Container) ArrayClear() error {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	} else if !CBoolTest(C.IsArray(ct.ct)) {
 		return ErrNotArray
 	} else {
 		C.ArrayClear(ct.ct)
+		changed(ct)
 	}
 	return nil
 }
 func (ct *Container) ArrayRemove(index int) error {
+	defer runtime.KeepAlive(ct)
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	} else if !CBoolTest(C.IsArray(ct.ct)) {
 		return ErrNotArray
 	} else if int(C.ValArraySize(ct.ct)) <= index {
 		return ErrOutOfBounds
 	} else {
 		C.ArrayRemove(ct.ct, C.int(index))
+		changed(ct)
 	}
 
 	return nil
@@ -813,6 +1211,8 @@ func (ct *Container) ArrayRemove(index int) error {
 func (ct *Container) RemoveMemberAtPath(path string) error {
 	if ct == nil {
 		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
 	}
 	parts := strings.Split(path, ".")
 	if len(parts) >= 1 {
@@ -844,6 +1244,7 @@ func (ct *Container) RemoveMemberAtPath(path string) error {
 	return nil
 }
 func (ct *Container) StripNulls(leaveEmptyArray bool) *Container {
+	defer runtime.KeepAlive(ct)
 	switch ct.GetType() {
 	case TypeArray:
 		arr, _, _ := ct.GetArray()
@@ -858,6 +1259,7 @@ func (ct *Container) StripNulls(leaveEmptyArray bool) *Container {
 			for _, i := range removes {
 				C.ArrayRemove(ct.ct, C.int(i))
 			}
+			changed(ct)
 			return ct
 		} else {
 			return nil
@@ -894,7 +1296,8 @@ func (ct *Container) StripNulls(leaveEmptyArray bool) *Container {
 
 // new style - no errors (returns nil instead), can be chained
 func (ct *Container) GetMemberCountOrNil() int {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return 0
 	} else if CBoolTest(C.IsObj(ct.ct)) {
 		return int(C.GetMemberCount(ct.ct))
@@ -929,17 +1332,14 @@ func (ct *Container) GetMemberMapOrNil() map[string]*Container {
 }
 
 func (ct *Container) GetMemberOrNil(key string) *Container {
-	if ct == nil {
+	defer runtime.KeepAlive(ct)
+	if ct == nil || ct.stale() {
 		return nil
 	}
-	cStr := C.CString(key)
-	defer C.free(unsafe.Pointer(cStr))
+	cStr, size := stringToC(key)
 	if CBoolTest(C.IsObj(ct.ct)) {
 		if ct.HasMember(key) {
-			var m Container
-			m.doc = ct.doc
-			m.ct = C.GetMember(ct.ct, cStr)
-			return &m
+			return ct.derive(C.GetMember(ct.ct, cStr, size))
 		} else {
 			return nil
 		}
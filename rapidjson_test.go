@@ -1,6 +1,8 @@
 package rapidjson
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"fmt"
@@ -56,6 +58,61 @@ func TestParse(t *testing.T) {
 	defer json2.Free()
 }
 
+func TestParseError(t *testing.T) {
+	input := "{\n  \"member1\" : 12345,\n  \"member2\" 2\n}"
+	json, err := NewParsedStringJson(input)
+	defer json.Free()
+	assert.True(t, errors.Is(err, ErrJsonParse), "should be a parse error")
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "should be a *ParseError")
+	assert.Equal(t, ParseErrorObjectMissColon, parseErr.Code)
+	assert.Equal(t, strings.Index(input, "2\n}"), parseErr.Offset)
+	assert.Equal(t, 3, parseErr.Line)
+	assert.Equal(t, 13, parseErr.Column)
+	assert.Equal(t, " 12345,\n  \"member2\" 2\n}", parseErr.Context)
+
+	long := "[" + strings.Repeat("1,", 1000000) + "]"
+	json2, err := NewParsedStringJson(long)
+	defer json2.Free()
+	assert.True(t, errors.As(err, &parseErr), "should be a *ParseError")
+	assert.Equal(t, ParseErrorValueInvalid, parseErr.Code)
+	assert.Equal(t, len(long)-1, parseErr.Offset)
+	assert.True(t, len(err.Error()) < 200, "should not include the whole input")
+}
+
+func TestParseWithOptions(t *testing.T) {
+	input := `{
+        // comment
+        "member1" : [1, 2, 3,],
+        /* block comment */
+        "member2" : NaN,
+    }`
+
+	json, err := NewParsedStringJson(input)
+	assert.NotNil(t, err, "should error on comments without options")
+	json.Free()
+
+	opts := ParseOptions{Comments: true, TrailingCommas: true, NanAndInf: true}
+	json, err = NewParsedStringJsonWithOptions(input, opts)
+	assert.Nil(t, err, "should not error on parsing with options")
+	defer json.Free()
+	member1, err := json.GetContainer().GetMemberOrNil("member1").GetIntArray()
+	assert.Nil(t, err, "should not error on member1")
+	assert.Equal(t, []int64{1, 2, 3}, member1)
+	_, err = json.GetContainer().GetMemberOrNil("member2").GetFloat()
+	assert.Nil(t, err, "should not error on member2")
+
+	json2, err := NewParsedJsonWithOptions([]byte(`[1, 2, 3,]`), ParseOptions{TrailingCommas: true, Iterative: true})
+	assert.Nil(t, err, "should not error on iterative parsing")
+	defer json2.Free()
+	assert.Equal(t, `[1,2,3]`, json2.String())
+
+	json3, err := NewParsedStringJsonWithOptions("[\"\xff\"]", ParseOptions{ValidateEncoding: true})
+	assert.NotNil(t, err, "should error on invalid encoding")
+	json3.Free()
+}
+
 func TestOutput(t *testing.T) {
 	json, err := NewParsedStringJson(testJSON1)
 	assert.Nil(t, err, "should not error on parsing")
@@ -134,6 +191,37 @@ func TestGetValue(t *testing.T) {
 	assert.Equal(t, "rapidjson is awesome!", member4)
 }
 
+func TestUnsigned(t *testing.T) {
+	json, err := NewParsedStringJson(`{"hash": 18446744073709551615, "small": 7, "neg": -1, "big": 4294967296}`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	ct := json.GetContainer()
+	hash, err := ct.GetMemberOrNil("hash").GetUint64()
+	assert.Nil(t, err, "should not error on hash")
+	assert.Equal(t, uint64(18446744073709551615), hash)
+	value, err := ct.GetMemberOrNil("hash").GetValue()
+	assert.Nil(t, err, "should not error on hash value")
+	assert.Equal(t, uint64(18446744073709551615), value)
+	_, err = ct.GetMemberOrNil("hash").GetInt64()
+	assert.Equal(t, ErrNotInt, err)
+
+	small, err := ct.GetMemberOrNil("small").GetUint()
+	assert.Nil(t, err, "should not error on small")
+	assert.Equal(t, uint(7), small)
+	value, _ = ct.GetMemberOrNil("small").GetValue()
+	assert.Equal(t, int64(7), value)
+	_, err = ct.GetMemberOrNil("neg").GetUint64()
+	assert.Equal(t, ErrNotUint, err)
+	_, err = ct.GetMemberOrNil("big").GetUint()
+	assert.Equal(t, ErrNotUint, err)
+
+	assert.Nil(t, ct.SetMemberValue("hash", uint64(18446744073709551614)))
+	assert.Nil(t, ct.SetMemberValue("small", uint32(4294967295)))
+	assert.Nil(t, ct.SetMemberValue("big", uint(1)))
+	assert.Equal(t, `{"hash":18446744073709551614,"small":4294967295,"neg":-1,"big":1}`, json.String())
+}
+
 func TestSetters(t *testing.T) {
 	json, err := NewParsedStringJson(testJSON1)
 	assert.Nil(t, err, "should not error on parsing")
@@ -225,6 +313,38 @@ func TestCopy(t *testing.T) {
 	assert.Equal(t, expected, dest.String())
 }
 
+func TestEmbeddedNul(t *testing.T) {
+	input := `{"a\u0000b":"c\u0000d","e":["\u0000"]}`
+	json, err := NewParsedStringJson(input)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	ct := json.GetContainer()
+	assert.Equal(t, []string{"a\x00b", "e"}, ct.GetMemberNamesOrNil())
+	assert.True(t, ct.HasMember("a\x00b"))
+	assert.False(t, ct.HasMember("a"))
+	value, err := ct.GetMemberOrNil("a\x00b").GetString()
+	assert.Nil(t, err, "should not error on member with NUL")
+	assert.Equal(t, "c\x00d", value)
+	assert.Equal(t, input, json.String())
+
+	err = ct.AddValue("f\x00", "g\x00h")
+	assert.Nil(t, err, "should not error on adding member with NUL")
+	value, err = ct.GetMemberOrNil("f\x00").GetString()
+	assert.Nil(t, err, "should not error on added member with NUL")
+	assert.Equal(t, "g\x00h", value)
+
+	err = ct.RemoveMember("a\x00b")
+	assert.Nil(t, err, "should not error on removing member with NUL")
+	assert.Equal(t, `{"f\u0000":"g\u0000h","e":["\u0000"]}`, json.String())
+	assert.Equal(t, []byte(json.String()), json.Bytes())
+
+	json2, err := NewParsedJson(json.Bytes())
+	assert.Nil(t, err, "should not error on parsing output")
+	defer json2.Free()
+	assert.True(t, json2.GetContainer().IsEqual(ct))
+}
+
 func TestNil(t *testing.T) {
 	var ct *Container
 
@@ -253,3 +373,36 @@ func TestSwap(t *testing.T) {
 
 	json1.Free()
 }
+
+func TestReset(t *testing.T) {
+	json, err := NewParsedStringJson(`{"a": [1, 2, 3]}`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	json.NewContainerObj()
+	json.Reset()
+	assert.Equal(t, "null", json.String())
+	assert.Equal(t, 0, json.GetAllocated())
+	assert.Equal(t, 0, json.MemoryStats().Allocated)
+	assert.Nil(t, json.ParseString(`{"b": true}`), "should not error on reuse")
+	assert.Equal(t, `{"b":true}`, json.String())
+
+	// a parse error doesn't stick
+	assert.NotNil(t, json.ParseString(`{`))
+	json.Reset()
+	assert.False(t, json.HasParseError())
+
+	// the pool keeps the memory it grew to, in one buffer
+	pool := NewDocWithOptions(DocOptions{Allocator: AllocatorPool})
+	defer pool.Free()
+	input := `[` + strings.Repeat(`"a string long enough to need its own allocation",`, 5000) + `0]`
+	assert.Nil(t, pool.ParseString(input), "should not error on parsing")
+	grown := pool.MemoryStats().Allocated
+	assert.True(t, grown > 64*1024)
+	for i := 0; i < 3; i++ {
+		pool.Reset()
+		assert.True(t, pool.MemoryStats().Allocated >= grown)
+		assert.Nil(t, pool.ParseString(input), "should not error on reuse")
+	}
+	assert.True(t, pool.MemoryStats().Allocated <= grown+1024, "should not grow across resets")
+	assert.Equal(t, 5001, len(pool.GetContainer().GetArrayOrNil()))
+}
@@ -2,23 +2,279 @@
 #include "rapidjson/writer.h"
 #include "rapidjson/prettywriter.h"
 #include "rapidjson/stringbuffer.h"
+#include "rapidjson/pointer.h"
+#include "rapidjson/schema.h"
 #include "rjwrapper.h"
+#include <cmath>
+#include <cstdlib>
+#include <cstring>
 #include <iostream>
+#include <map>
 #include <sstream>
+#include <string>
 #include <stdint.h>
 
-// default to using CrtAllocator
-typedef rapidjson::GenericDocument<rapidjson::UTF8<>, rapidjson::CrtAllocator> Document;
-typedef rapidjson::GenericValue<rapidjson::UTF8<>, rapidjson::CrtAllocator> Value;
+// AllocStats counts the bytes a Doc holds from the system. CRT blocks can
+// outlive their allocator when moved to another Doc, so they keep a
+// reference to the stats
+struct AllocStats {
+    size_t used;
+    size_t peak;
+    size_t refs;
+
+    AllocStats() : used(0), peak(0), refs(1) {}
+    void Add(size_t size) {
+        used += size;
+        if (used > peak) {
+            peak = used;
+        }
+    }
+};
+
+// AllocHeader starts every block, Free is static in the rapidjson Allocator
+// concept so the block has to tell who owns it
+struct AllocHeader {
+    AllocStats *stats;
+    size_t size;
+};
+static const size_t kPooled = (size_t)-1;
+
+static void *HeaderMalloc(AllocStats *stats, size_t size) {
+    AllocHeader *h = (AllocHeader *)std::malloc(sizeof(AllocHeader) + size);
+    if (!h) {
+        return NULL;
+    }
+    h->stats = stats;
+    h->size = size;
+    if (stats) {
+        stats->refs++;
+        stats->Add(size);
+    }
+    return h + 1;
+}
+static void HeaderFree(void *ptr) {
+    AllocHeader *h = (AllocHeader *)ptr - 1;
+    if (h->stats) {
+        h->stats->used -= h->size;
+        if (--h->stats->refs == 0) {
+            delete h->stats;
+        }
+    }
+    std::free(h);
+}
+
+// ChunkAllocator gets the memory pool its chunks, counted in the stats
+class ChunkAllocator {
+public:
+    static const bool kNeedFree = true;
+
+    ChunkAllocator(AllocStats *stats = NULL) : stats_(stats) {}
+    void *Malloc(size_t size) {
+        return size ? HeaderMalloc(stats_, size) : NULL;
+    }
+    void *Realloc(void *originalPtr, size_t originalSize, size_t newSize) {
+        void *ptr = Malloc(newSize);
+        if (ptr && originalPtr) {
+            std::memcpy(ptr, originalPtr, originalSize < newSize ? originalSize : newSize);
+        }
+        Free(originalPtr);
+        return ptr;
+    }
+    void Free(void *ptr) {
+        if (ptr) {
+            HeaderFree(ptr);
+        }
+    }
+
+private:
+    AllocStats *stats_;
+};
+typedef rapidjson::MemoryPoolAllocator<ChunkAllocator> PoolAllocator;
+static const size_t kPoolChunkCapacity = 64 * 1024; // rapidjson's default
+
+// JsonAllocator is the allocator of every Value, picked per Doc at runtime:
+// malloc per block like rapidjson::CrtAllocator, or a memory pool freed all
+// at once with the Doc. Allocators made by rapidjson itself are plain CRT
+// ones without stats
+class JsonAllocator {
+public:
+    static const bool kNeedFree = true;
+
+    JsonAllocator() : stats_(NULL), chunks_(NULL), pool_(NULL), buffer_(NULL), bufferSize_(0) {}
+    JsonAllocator(int kind, size_t bufferSize) : stats_(new AllocStats()), chunks_(NULL), pool_(NULL), buffer_(NULL), bufferSize_(0) {
+        if (kind != JsonAllocatorPool) {
+            return;
+        }
+        chunks_ = new ChunkAllocator(stats_);
+        // the pool needs room for its chunk header in a caller buffer
+        if (bufferSize > 64) {
+            buffer_ = chunks_->Malloc(bufferSize);
+        }
+        if (buffer_) {
+            bufferSize_ = bufferSize;
+            pool_ = new PoolAllocator(buffer_, bufferSize, kPoolChunkCapacity, chunks_);
+        } else {
+            pool_ = new PoolAllocator(kPoolChunkCapacity, chunks_);
+        }
+    }
+    ~JsonAllocator() {
+        delete pool_;
+        if (buffer_) {
+            chunks_->Free(buffer_);
+        }
+        delete chunks_;
+        if (stats_ && --stats_->refs == 0) {
+            delete stats_;
+        }
+    }
+
+    void *Malloc(size_t size) {
+        if (!size) {
+            return NULL;
+        } else if (!pool_) {
+            return HeaderMalloc(stats_, size);
+        }
+        AllocHeader *h = (AllocHeader *)pool_->Malloc(sizeof(AllocHeader) + size);
+        if (!h) {
+            return NULL;
+        }
+        h->stats = NULL;
+        h->size = kPooled;
+        return h + 1;
+    }
+    void *Realloc(void *originalPtr, size_t originalSize, size_t newSize) {
+        if (!originalPtr) {
+            return Malloc(newSize);
+        } else if (!newSize) {
+            Free(originalPtr);
+            return NULL;
+        }
+        AllocHeader *h = (AllocHeader *)originalPtr - 1;
+        if (!pool_ && h->size != kPooled && h->stats == stats_) {
+            size_t oldSize = h->size;
+            h = (AllocHeader *)std::realloc(h, sizeof(AllocHeader) + newSize);
+            if (!h) {
+                return NULL;
+            }
+            h->size = newSize;
+            if (stats_) {
+                stats_->used -= oldSize;
+                stats_->Add(newSize);
+            }
+            return h + 1;
+        }
+        // pool blocks and blocks of other Docs move
+        void *ptr = Malloc(newSize);
+        if (ptr) {
+            std::memcpy(ptr, originalPtr, originalSize < newSize ? originalSize : newSize);
+            Free(originalPtr);
+        }
+        return ptr;
+    }
+    static void Free(void *ptr) {
+        if (ptr && ((AllocHeader *)ptr - 1)->size != kPooled) {
+            HeaderFree(ptr);
+        }
+    }
+
+    // Reset drops every pool block once the values are gone. The pool keeps
+    // its capacity, chunks it grew are merged into one buffer
+    void Reset() {
+        if (!pool_) {
+            return;
+        }
+        // the stats of a pool count its buffer and chunks
+        size_t held = stats_->used;
+        if (held <= bufferSize_) {
+            pool_->Clear();
+            return;
+        }
+        delete pool_;
+        if (buffer_) {
+            chunks_->Free(buffer_);
+        }
+        bufferSize_ = held;
+        buffer_ = chunks_->Malloc(bufferSize_);
+        if (buffer_) {
+            pool_ = new PoolAllocator(buffer_, bufferSize_, kPoolChunkCapacity, chunks_);
+        } else {
+            bufferSize_ = 0;
+            pool_ = new PoolAllocator(kPoolChunkCapacity, chunks_);
+        }
+    }
+
+    size_t Allocated() const {
+        return stats_ ? stats_->used : 0;
+    }
+    size_t Peak() const {
+        return stats_ ? stats_->peak : 0;
+    }
+
+private:
+    JsonAllocator(const JsonAllocator &);
+    JsonAllocator &operator=(const JsonAllocator &);
+
+    AllocStats *stats_;
+    ChunkAllocator *chunks_;
+    PoolAllocator *pool_;
+    void *buffer_;
+    size_t bufferSize_;
+};
+
+typedef rapidjson::GenericDocument<rapidjson::UTF8<>, JsonAllocator> Document;
+typedef rapidjson::GenericValue<rapidjson::UTF8<>, JsonAllocator> Value;
+typedef rapidjson::GenericPointer<Value, JsonAllocator> Pointer;
+typedef rapidjson::GenericSchemaDocument<Value, JsonAllocator> SchemaDocument;
+typedef rapidjson::GenericSchemaValidator<SchemaDocument> SchemaValidator;
+
+// JsonAllocatorHolder is a base of JsonDocument ahead of Document, so the
+// allocator is built before the Document and outlives its values
+struct JsonAllocatorHolder {
+    JsonAllocatorHolder(int kind, size_t bufferSize) : allocator(kind, bufferSize) {}
+    JsonAllocator allocator;
+};
+
+// JsonDocument keeps wrapper state next to the rapidjson Document. JsonDoc
+// handles point at the Document base, so they can be used as a Document or
+// a Value directly
+class JsonDocument : public JsonAllocatorHolder, public Document {
+public:
+    JsonDocument(int kind, size_t bufferSize) : JsonAllocatorHolder(kind, bufferSize), Document(&allocator) {}
+
+    rapidjson::ParseResult parseResult;
+};
+
+static JsonDocument *ToJsonDocument(JsonDoc json) {
+    return static_cast<JsonDocument *>((Document *)json);
+}
 
 JsonDoc JsonInit() {
-    Document *doc = new Document();
+    return JsonInitAllocator(JsonAllocatorCrt, 0);
+}
 
-    return (void *)doc;
+JsonDoc JsonInitAllocator(int kind, size_t bufferSize) {
+    JsonDocument *doc = new JsonDocument(kind, bufferSize);
+
+    return (void *)static_cast<Document *>(doc);
+}
+
+void JsonReset(JsonDoc json) {
+    JsonDocument *doc = ToJsonDocument(json);
+
+    doc->SetNull();
+    doc->parseResult.Clear();
+    doc->allocator.Reset();
+}
+
+void JsonMemoryStats(JsonDoc json, size_t *allocated, size_t *peak) {
+    JsonDocument *doc = ToJsonDocument(json);
+
+    *allocated = doc->allocator.Allocated();
+    *peak = doc->allocator.Peak();
 }
 
 void JsonFree(JsonDoc json) {
-    Document *doc = (Document *)json;
+    JsonDocument *doc = ToJsonDocument(json);
 
     delete doc;
 }
@@ -35,77 +291,549 @@ void ValFree(JsonVal value) {
     delete val;
 }
 
-void JsonParse(JsonDoc json, char *input) {
-    ((Document *)json)->Parse(input);
+// ChunkStream is a rapidjson input stream over a buffer. With a read func
+// the buffer is refilled in chunks as the parser consumes it, without one
+// it is a plain memory stream over the whole input
+typedef size_t (*ChunkReadFunc)(void *ctx, char *buffer, size_t size);
+
+class ChunkStream {
+public:
+    typedef char Ch;
+
+    ChunkStream(const char *input, size_t length)
+        : read_(0), ctx_(0), buffer_(0), bufferSize_(0), begin_(input), cur_(input), end_(input + length), count_(0) {
+        SkipBOM();
+    }
+    ChunkStream(ChunkReadFunc read, void *ctx, char *buffer, size_t bufferSize)
+        : read_(read), ctx_(ctx), buffer_(buffer), bufferSize_(bufferSize), begin_(buffer), cur_(buffer), end_(buffer), count_(0) {
+        Fill();
+        SkipBOM();
+    }
+
+    Ch Peek() const { return cur_ != end_ ? *cur_ : '\0'; }
+    Ch Take() {
+        if (cur_ == end_) {
+            return '\0';
+        }
+        Ch c = *cur_++;
+        if (cur_ == end_) {
+            Fill();
+        }
+        return c;
+    }
+    size_t Tell() const { return count_ + static_cast<size_t>(cur_ - begin_); }
+
+    // not implemented, input only
+    void Put(Ch) { RAPIDJSON_ASSERT(false); }
+    void Flush() { RAPIDJSON_ASSERT(false); }
+    Ch* PutBegin() { RAPIDJSON_ASSERT(false); return 0; }
+    size_t PutEnd(Ch*) { RAPIDJSON_ASSERT(false); return 0; }
+
+private:
+    void Fill() {
+        if (!read_) {
+            return;
+        }
+        count_ += static_cast<size_t>(end_ - begin_);
+        size_t n = read_(ctx_, buffer_, bufferSize_);
+        begin_ = cur_ = buffer_;
+        end_ = buffer_ + n;
+    }
+    void SkipBOM() {
+        if (end_ - cur_ >= 3 && (unsigned char)cur_[0] == 0xEF && (unsigned char)cur_[1] == 0xBB && (unsigned char)cur_[2] == 0xBF) {
+            Take();
+            Take();
+            Take();
+        }
+    }
+
+    ChunkReadFunc read_;
+    void *ctx_;
+    char *buffer_;
+    size_t bufferSize_;
+    const char *begin_;
+    const char *cur_;
+    const char *end_;
+    size_t count_;
+};
+
+// Parse<flags> is a template, so runtime flags are dispatched one bit at a
+// time to the matching instantiation
+template <unsigned i> struct ParseFlagAt;
+template <> struct ParseFlagAt<0> { static const unsigned value = JsonParseValidateEncoding; };
+template <> struct ParseFlagAt<1> { static const unsigned value = JsonParseIterative; };
+template <> struct ParseFlagAt<2> { static const unsigned value = JsonParseFullPrecision; };
+template <> struct ParseFlagAt<3> { static const unsigned value = JsonParseComments; };
+template <> struct ParseFlagAt<4> { static const unsigned value = JsonParseTrailingCommas; };
+template <> struct ParseFlagAt<5> { static const unsigned value = JsonParseNanAndInf; };
+template <> struct ParseFlagAt<6> { static const unsigned value = JsonParseNumbersAsStrings; };
+static const unsigned kParseFlagCount = 7;
+
+// Lossless numbers keep their text in a one member object whose name is a
+// const string pointing at kRawNumberName. Only the address marks them, so
+// the mark survives copies and moves, and parsed input can't forge it
+static const char kRawNumberName[] = "number";
+
+static bool IsRawNumber(const Value &v) {
+    return v.IsObject() && v.MemberCount() == 1 && v.MemberBegin()->name.GetString() == kRawNumberName;
+}
+static const Value &RawNumberText(const Value &v) {
+    return v.MemberBegin()->value;
+}
+
+// NumberHandler keeps the number read from a lossless number's text
+class NumberHandler : public rapidjson::BaseReaderHandler<rapidjson::UTF8<>, NumberHandler> {
+public:
+    NumberHandler(Value &number) : number_(number) {}
+
+    bool Int(int i) { number_.SetInt(i); return true; }
+    bool Uint(unsigned u) { number_.SetUint(u); return true; }
+    bool Int64(int64_t i) { number_.SetInt64(i); return true; }
+    bool Uint64(uint64_t u) { number_.SetUint64(u); return true; }
+    bool Double(double d) { number_.SetDouble(d); return true; }
+
+private:
+    Value &number_;
+};
+
+static const Value &ParseNumber(const char *str, size_t length, Value &number) {
+    ChunkStream is(str, length);
+    NumberHandler handler(number);
+    rapidjson::GenericReader<rapidjson::UTF8<>, rapidjson::UTF8<>, rapidjson::CrtAllocator> reader;
+    reader.Parse<rapidjson::kParseFullPrecisionFlag | rapidjson::kParseNanAndInfFlag>(is, handler);
+    return number;
+}
+
+// AsNumber returns a lossless number as a plain number parsed into parsed,
+// any other value is returned as is
+static const Value &AsNumber(const Value &v, Value &parsed) {
+    if (!IsRawNumber(v)) {
+        return v;
+    }
+    const Value &text = RawNumberText(v);
+    return ParseNumber(text.GetString(), text.GetStringLength(), parsed);
+}
+
+// AcceptRawNumber hands a lossless number to a handler. Writers get the
+// original text, other handlers such as schema validators the parsed number
+template <typename Handler>
+static bool AcceptRawNumber(const Value &v, Handler &handler) {
+    Value parsed;
+    return AsNumber(v, parsed).Accept(handler);
+}
+static bool IsNanOrInfText(const char *str) {
+    const char *digits = str[0] == '-' ? str + 1 : str;
+    return digits[0] == 'N' || digits[0] == 'I';
+}
+template <typename Writer>
+static bool WriteRawNumber(const Value &v, Writer &writer) {
+    const Value &text = RawNumberText(v);
+    const char *str = text.GetString();
+    // NaN and Inf go through Double, so the writer can refuse them
+    if (IsNanOrInfText(str)) {
+        Value parsed;
+        return writer.Double(AsNumber(v, parsed).GetDouble());
+    }
+    return writer.RawValue(str, text.GetStringLength(), rapidjson::kNumberType);
+}
+template <typename OS, typename SE, typename TE, typename A, unsigned F>
+static bool AcceptRawNumber(const Value &v, rapidjson::Writer<OS, SE, TE, A, F> &writer) {
+    return WriteRawNumber(v, writer);
+}
+template <typename OS, typename SE, typename TE, typename A, unsigned F>
+static bool AcceptRawNumber(const Value &v, rapidjson::PrettyWriter<OS, SE, TE, A, F> &writer) {
+    return WriteRawNumber(v, writer);
+}
+
+// AcceptValue is Value::Accept with lossless numbers sent as numbers
+template <typename Handler>
+static bool AcceptValue(const Value &v, Handler &handler) {
+    switch (v.GetType()) {
+    case rapidjson::kObjectType:
+        if (IsRawNumber(v)) {
+            return AcceptRawNumber(v, handler);
+        }
+        if (!handler.StartObject()) {
+            return false;
+        }
+        for (Value::ConstMemberIterator itr = v.MemberBegin(); itr != v.MemberEnd(); ++itr) {
+            if (!handler.Key(itr->name.GetString(), itr->name.GetStringLength(), true) || !AcceptValue(itr->value, handler)) {
+                return false;
+            }
+        }
+        return handler.EndObject(v.MemberCount());
+    case rapidjson::kArrayType:
+        if (!handler.StartArray()) {
+            return false;
+        }
+        for (Value::ConstValueIterator itr = v.Begin(); itr != v.End(); ++itr) {
+            if (!AcceptValue(*itr, handler)) {
+                return false;
+            }
+        }
+        return handler.EndArray(v.Size());
+    default:
+        return v.Accept(handler);
+    }
+}
+
+// ParseHandler forwards SAX events to the document being built, checking
+// each one against a schema first when validating
+class ParseHandler {
+public:
+    typedef char Ch;
+
+    ParseHandler(Document &doc, SchemaValidator *validator) : doc_(doc), validator_(validator) {}
+
+    bool Null() { return (!validator_ || validator_->Null()) && doc_.Null(); }
+    bool Bool(bool b) { return (!validator_ || validator_->Bool(b)) && doc_.Bool(b); }
+    bool Int(int i) { return (!validator_ || validator_->Int(i)) && doc_.Int(i); }
+    bool Uint(unsigned i) { return (!validator_ || validator_->Uint(i)) && doc_.Uint(i); }
+    bool Int64(int64_t i) { return (!validator_ || validator_->Int64(i)) && doc_.Int64(i); }
+    bool Uint64(uint64_t i) { return (!validator_ || validator_->Uint64(i)) && doc_.Uint64(i); }
+    bool Double(double d) { return (!validator_ || validator_->Double(d)) && doc_.Double(d); }
+    // with JsonParseNumbersAsStrings numbers arrive as text, which is kept
+    // as a lossless number
+    bool RawNumber(const Ch *str, rapidjson::SizeType length, bool) {
+        Value number;
+        return (!validator_ || ParseNumber(str, length, number).Accept(*validator_)) &&
+            doc_.StartObject() && doc_.Key(kRawNumberName, sizeof(kRawNumberName) - 1, false) &&
+            doc_.String(str, length, true) && doc_.EndObject(1);
+    }
+    bool String(const Ch *str, rapidjson::SizeType length, bool copy) {
+        return (!validator_ || validator_->String(str, length, copy)) && doc_.String(str, length, copy);
+    }
+    bool StartObject() { return (!validator_ || validator_->StartObject()) && doc_.StartObject(); }
+    bool Key(const Ch *str, rapidjson::SizeType length, bool copy) {
+        return (!validator_ || validator_->Key(str, length, copy)) && doc_.Key(str, length, copy);
+    }
+    bool EndObject(rapidjson::SizeType memberCount) {
+        return (!validator_ || validator_->EndObject(memberCount)) && doc_.EndObject(memberCount);
+    }
+    bool StartArray() { return (!validator_ || validator_->StartArray()) && doc_.StartArray(); }
+    bool EndArray(rapidjson::SizeType elementCount) {
+        return (!validator_ || validator_->EndArray(elementCount)) && doc_.EndArray(elementCount);
+    }
+
+private:
+    Document &doc_;
+    SchemaValidator *validator_;
+};
+
+// ParseGenerator runs the reader for Document::Populate, which only takes
+// the result into the document when the whole input was accepted
+template <unsigned parseFlags>
+struct ParseGenerator {
+    ParseGenerator(ChunkStream &is, SchemaValidator *validator) : is(is), validator(validator) {}
+
+    bool operator()(Document &doc) {
+        ParseHandler handler(doc, validator);
+        rapidjson::GenericReader<rapidjson::UTF8<>, rapidjson::UTF8<>, rapidjson::CrtAllocator> reader;
+        result = reader.Parse<parseFlags>(is, handler);
+        return !result.IsError();
+    }
+
+    ChunkStream &is;
+    SchemaValidator *validator;
+    rapidjson::ParseResult result;
+};
+
+template <unsigned parseFlags, unsigned i = 0>
+struct ParseDispatch {
+    static void Parse(JsonDocument *doc, ChunkStream &is, SchemaValidator *validator, unsigned flags) {
+        if (flags & ParseFlagAt<i>::value) {
+            ParseDispatch<parseFlags | ParseFlagAt<i>::value, i + 1>::Parse(doc, is, validator, flags);
+        } else {
+            ParseDispatch<parseFlags, i + 1>::Parse(doc, is, validator, flags);
+        }
+    }
+};
+template <unsigned parseFlags>
+struct ParseDispatch<parseFlags, kParseFlagCount> {
+    static void Parse(JsonDocument *doc, ChunkStream &is, SchemaValidator *validator, unsigned) {
+        ParseGenerator<parseFlags> g(is, validator);
+        doc->Populate(g);
+        doc->parseResult = g.result;
+    }
+};
+
+static_assert((unsigned)JsonParseValidateEncoding == (unsigned)rapidjson::kParseValidateEncodingFlag, "parse flag mismatch");
+static_assert((unsigned)JsonParseIterative == (unsigned)rapidjson::kParseIterativeFlag, "parse flag mismatch");
+static_assert((unsigned)JsonParseFullPrecision == (unsigned)rapidjson::kParseFullPrecisionFlag, "parse flag mismatch");
+static_assert((unsigned)JsonParseComments == (unsigned)rapidjson::kParseCommentsFlag, "parse flag mismatch");
+static_assert((unsigned)JsonParseTrailingCommas == (unsigned)rapidjson::kParseTrailingCommasFlag, "parse flag mismatch");
+static_assert((unsigned)JsonParseNanAndInf == (unsigned)rapidjson::kParseNanAndInfFlag, "parse flag mismatch");
+static_assert((unsigned)JsonParseNumbersAsStrings == (unsigned)rapidjson::kParseNumbersAsStringsFlag, "parse flag mismatch");
+
+void JsonParse(JsonDoc json, const char *input, size_t length) {
+    JsonParseFlags(json, input, length, 0);
+}
+
+void JsonParseFlags(JsonDoc json, const char *input, size_t length, unsigned flags) {
+    ChunkStream is(input, length);
+    ParseDispatch<rapidjson::kParseDefaultFlags>::Parse(ToJsonDocument(json), is, NULL, flags);
+}
+
+static size_t ReadGoChunk(void *ctx, char *buffer, size_t size) {
+    return goReadChunk((uintptr_t)ctx, buffer, size);
+}
+
+void JsonParseReader(JsonDoc json, uintptr_t reader, char *buffer, size_t size, unsigned flags) {
+    ChunkStream is(ReadGoChunk, (void *)reader, buffer, size);
+    ParseDispatch<rapidjson::kParseDefaultFlags>::Parse(ToJsonDocument(json), is, NULL, flags);
 }
 
 int HasParseError(JsonDoc json) {
-    return ((Document *)json)->HasParseError();
+    return ToJsonDocument(json)->parseResult.IsError();
 }
 
 int GetParseErrorCode(JsonDoc json) {
-    return ((Document *)json)->GetParseError();
+    return ToJsonDocument(json)->parseResult.Code();
 }
 int64_t GetParseErrorOffset(JsonDoc json) {
-    return ((Document *)json)->GetErrorOffset();
+    return ToJsonDocument(json)->parseResult.Offset();
+}
+
+// ValuesEqual is Value::operator== aware of lossless numbers, compared as
+// text between themselves and by parsed value against other numbers
+static bool ValuesEqual(const Value &a, const Value &b) {
+    bool rawA = IsRawNumber(a), rawB = IsRawNumber(b);
+    if (rawA && rawB) {
+        return RawNumberText(a) == RawNumberText(b);
+    } else if (rawA || rawB) {
+        Value parsedA, parsedB;
+        return AsNumber(a, parsedA) == AsNumber(b, parsedB);
+    }
+    switch (a.GetType()) {
+    case rapidjson::kObjectType:
+        if (!b.IsObject() || a.MemberCount() != b.MemberCount()) {
+            return false;
+        }
+        for (Value::ConstMemberIterator itr = a.MemberBegin(); itr != a.MemberEnd(); ++itr) {
+            Value::ConstMemberIterator other = b.FindMember(itr->name);
+            if (other == b.MemberEnd() || !ValuesEqual(itr->value, other->value)) {
+                return false;
+            }
+        }
+        return true;
+    case rapidjson::kArrayType:
+        if (!b.IsArray() || a.Size() != b.Size()) {
+            return false;
+        }
+        for (rapidjson::SizeType i = 0; i < a.Size(); i++) {
+            if (!ValuesEqual(a[i], b[i])) {
+                return false;
+            }
+        }
+        return true;
+    default:
+        return a == b;
+    }
 }
 
 int IsValEqual(JsonVal val1, JsonVal val2) {
-    Value * v1 = (Value *)val1;
-    const Value * v2 = (const Value *)val2;
-    return (*v1)==(*v2);
+    return ValuesEqual(*(const Value *)val1, *(const Value *)val2);
+}
+
+// copies the buffer out with its length, the caller frees the result. A
+// failed write gives NULL rather than partial output
+static char *BufferCopy(const rapidjson::StringBuffer &buffer, bool ok, size_t *length) {
+    if (!ok) {
+        *length = 0;
+        return NULL;
+    }
+    *length = buffer.GetSize();
+    char *result = (char *)malloc(*length + 1);
+    memcpy(result, buffer.GetString(), *length + 1);
+
+    return result;
 }
 
-char *GetString(JsonDoc json) {
+char *GetString(JsonDoc json, size_t *length) {
     rapidjson::StringBuffer buffer;
     rapidjson::Writer<rapidjson::StringBuffer> writer(buffer);
-    ((Document *)json)->Accept(writer);
-    char *result = strdup(buffer.GetString());
+    bool ok = AcceptValue(*(Document *)json, writer);
 
-    return result;
+    return BufferCopy(buffer, ok, length);
 }
 
-char *GetPrettyString(JsonDoc json) {
+char *GetPrettyString(JsonDoc json, size_t *length) {
     rapidjson::StringBuffer buffer;
     rapidjson::PrettyWriter<rapidjson::StringBuffer> writer(buffer);
-    ((Document *)json)->Accept(writer);
-    char *result = strdup(buffer.GetString());
+    bool ok = AcceptValue(*(Document *)json, writer);
 
-    return result;
+    return BufferCopy(buffer, ok, length);
+}
+
+// ChunkWriteStream is a rapidjson output stream that hands its buffer to a
+// write func whenever it fills up, so output is never held in full
+typedef int (*ChunkWriteFunc)(void *ctx, const char *buffer, size_t size);
+
+class ChunkWriteStream {
+public:
+    typedef char Ch;
+
+    ChunkWriteStream(ChunkWriteFunc write, void *ctx, char *buffer, size_t bufferSize)
+        : write_(write), ctx_(ctx), buffer_(buffer), cur_(buffer), end_(buffer + bufferSize), failed_(false) {}
+
+    void Put(Ch c) {
+        if (cur_ == end_) {
+            Flush();
+        }
+        *cur_++ = c;
+    }
+    void Flush() {
+        if (cur_ != buffer_ && !failed_) {
+            failed_ = write_(ctx_, buffer_, static_cast<size_t>(cur_ - buffer_)) != 0;
+        }
+        cur_ = buffer_;
+    }
+    bool Failed() const { return failed_; }
+
+private:
+    ChunkWriteFunc write_;
+    void *ctx_;
+    char *buffer_;
+    char *cur_;
+    char *end_;
+    bool failed_;
+};
+
+static int WriteGoChunk(void *ctx, const char *buffer, size_t size) {
+    return goWriteChunk((uintptr_t)ctx, (char *)buffer, size);
+}
+
+template <unsigned writeFlags>
+static bool WriteValue(Value *value, ChunkWriteStream &os, const JsonWriteOptions *opts) {
+    typedef rapidjson::UTF8<> UTF8;
+    if (opts->pretty) {
+        rapidjson::PrettyWriter<ChunkWriteStream, UTF8, UTF8, rapidjson::CrtAllocator, writeFlags> writer(os);
+        writer.SetIndent(opts->indentChar, opts->indentCount);
+        if (opts->singleLineArray) {
+            writer.SetFormatOptions(rapidjson::kFormatSingleLineArray);
+        }
+        if (opts->maxDecimalPlaces > 0) {
+            writer.SetMaxDecimalPlaces(opts->maxDecimalPlaces);
+        }
+        return AcceptValue(*value, writer);
+    } else {
+        rapidjson::Writer<ChunkWriteStream, UTF8, UTF8, rapidjson::CrtAllocator, writeFlags> writer(os);
+        if (opts->maxDecimalPlaces > 0) {
+            writer.SetMaxDecimalPlaces(opts->maxDecimalPlaces);
+        }
+        return AcceptValue(*value, writer);
+    }
+}
+
+// HasNanOrInf finds values the writer refuses without kWriteNanAndInfFlag
+static bool HasNanOrInf(const Value &v) {
+    if (IsRawNumber(v)) {
+        return IsNanOrInfText(RawNumberText(v).GetString());
+    } else if (v.IsDouble()) {
+        return !std::isfinite(v.GetDouble());
+    } else if (v.IsObject()) {
+        for (Value::ConstMemberIterator itr = v.MemberBegin(); itr != v.MemberEnd(); ++itr) {
+            if (HasNanOrInf(itr->value)) {
+                return true;
+            }
+        }
+    } else if (v.IsArray()) {
+        for (Value::ConstValueIterator itr = v.Begin(); itr != v.End(); ++itr) {
+            if (HasNanOrInf(*itr)) {
+                return true;
+            }
+        }
+    }
+    return false;
+}
+
+// ValWrite streams value to w. NaN and Inf are refused up front rather
+// than midway, so a refused value writes nothing
+int ValWrite(JsonVal value, uintptr_t w, char *buffer, size_t size, const JsonWriteOptions *opts) {
+    ChunkWriteStream os(WriteGoChunk, (void *)w, buffer, size);
+    bool ok;
+    if (opts->nanAndInf) {
+        ok = WriteValue<rapidjson::kWriteNanAndInfFlag>((Value *)value, os, opts);
+    } else if (HasNanOrInf(*(Value *)value)) {
+        return 0;
+    } else {
+        ok = WriteValue<rapidjson::kWriteDefaultFlags>((Value *)value, os, opts);
+    }
+    if (ok) {
+        os.Flush();
+    }
+
+    return ok && !os.Failed();
 }
 
-int HasMember(JsonVal value, const char *member) {
-    return ((Value *)value)->HasMember(member);
+int HasMember(JsonVal value, const char *member, size_t length) {
+    Value key(rapidjson::StringRef(member, length));
+    return ((Value *)value)->HasMember(key);
 }
 
 int GetMemberCount(JsonVal value) {
     return ((Value *)value)->MemberCount();
 }
 
-char * GetMemberName(JsonVal value, int index) {
+const char *GetMemberName(JsonVal value, int index, size_t *length) {
     Value::ConstMemberIterator itr = ((Value *)value)->MemberBegin() + index;
-    std::string member = itr->name.GetString();
+    *length = itr->name.GetStringLength();
 
-    return strdup(member.c_str());
+    return itr->name.GetString();
 }
 
 int GetType(JsonVal value) {
-    return ((Value *)value)->GetType();
+    Value *val = (Value *)value;
+    return IsRawNumber(*val) ? rapidjson::kNumberType : val->GetType();
 }
 int IsObj(JsonVal value) {
-    return ((Value *)value)->IsObject();
+    Value *val = (Value *)value;
+    return val->IsObject() && !IsRawNumber(*val);
 }
 int IsInt(JsonVal value) {
-    return ((Value *)value)->IsInt();
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).IsInt();
 }
 int IsInt64(JsonVal value) {
-    return ((Value *)value)->IsInt64();
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).IsInt64();
+}
+int IsUint(JsonVal value) {
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).IsUint();
+}
+int IsUint64(JsonVal value) {
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).IsUint64();
+}
+int IsLosslessDouble(JsonVal value) {
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).IsLosslessDouble();
+}
+// the narrowest number kind that holds the value exactly
+int GetNumberKind(JsonVal value) {
+    Value parsed;
+    const Value &v = AsNumber(*(Value *)value, parsed);
+    if (!v.IsNumber()) {
+        return JsonNumberNone;
+    } else if (v.IsInt()) {
+        return JsonNumberInt32;
+    } else if (v.IsUint()) {
+        return JsonNumberUint32;
+    } else if (v.IsInt64()) {
+        return JsonNumberInt64;
+    } else if (v.IsUint64()) {
+        return JsonNumberUint64;
+    } else {
+        return JsonNumberDouble;
+    }
 }
 int IsString(JsonVal value) {
     return ((Value *)value)->IsString();
 }
 int IsDouble(JsonVal value) {
-    return ((Value *)value)->IsDouble();
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).IsDouble();
 }
 int IsArray(JsonVal value) {
     return ((Value *)value)->IsArray();
@@ -117,44 +845,65 @@ int IsNull(JsonVal value) {
     return ((Value *)value)->IsNull();
 }
 
-JsonVal GetMember(JsonVal value, const char * key) {
+JsonVal GetMember(JsonVal value, const char *k, size_t length) {
     Value *val = (Value *)value;
+    Value key(rapidjson::StringRef(k, length));
 
     Value& s = (*val)[key];
 
     return (void *) &s;
 }
 
-char *ValGetString(JsonVal value) {
+char *ValGetString(JsonVal value, size_t *length) {
     rapidjson::StringBuffer buffer;
     rapidjson::Writer<rapidjson::StringBuffer> writer(buffer);
-    ((Value *)value)->Accept(writer);
-    char *result = strdup(buffer.GetString());
+    bool ok = AcceptValue(*(Value *)value, writer);
 
-    return result;
+    return BufferCopy(buffer, ok, length);
 }
-char *ValGetPrettyString(JsonVal value) {
+char *ValGetPrettyString(JsonVal value, size_t *length) {
     rapidjson::StringBuffer buffer;
     rapidjson::PrettyWriter<rapidjson::StringBuffer> writer(buffer);
-    ((Value *)value)->Accept(writer);
-    char *result = strdup(buffer.GetString());
+    bool ok = AcceptValue(*(Value *)value, writer);
 
-    return result;
+    return BufferCopy(buffer, ok, length);
 }
 int ValGetInt(JsonVal value) {
-    return ((Value *)value)->GetInt();
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).GetInt();
 }
 int64_t ValGetInt64(JsonVal value) {
-    return ((Value *)value)->GetInt64();
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).GetInt64();
+}
+unsigned ValGetUint(JsonVal value) {
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).GetUint();
+}
+uint64_t ValGetUint64(JsonVal value) {
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).GetUint64();
 }
 double ValGetDouble(JsonVal value) {
-    return ((Value *)value)->GetDouble();
+    Value parsed;
+    return AsNumber(*(Value *)value, parsed).GetDouble();
 }
 int ValGetBool(JsonVal value) {
     return ((Value *)value)->GetBool();
 }
-char * ValGetBasicString(JsonVal value) {
-    return strdup( ((Value *)value)->GetString() );
+// returns the text of a lossless number, NULL for any other value
+const char *ValGetRawNumber(JsonVal value, size_t *length) {
+    Value *val = (Value *)value;
+    if (!IsRawNumber(*val)) {
+        *length = 0;
+        return NULL;
+    }
+    *length = RawNumberText(*val).GetStringLength();
+    return RawNumberText(*val).GetString();
+}
+const char *ValGetBasicString(JsonVal value, size_t *length) {
+    *length = ((Value *)value)->GetStringLength();
+    return ((Value *)value)->GetString();
 }
 
 int ValArraySize(JsonVal value) {
@@ -167,18 +916,101 @@ JsonVal GetArrayValueAt(JsonVal value, int index) {
     return (void *) &s;
 }
 
+static void EncodeSize(std::string &out, rapidjson::SizeType size) {
+    uint32_t n = size;
+    out.append((const char *)&n, sizeof(n));
+}
+static void EncodeString(std::string &out, const Value &v) {
+    EncodeSize(out, v.GetStringLength());
+    out.append(v.GetString(), v.GetStringLength());
+}
+static void EncodeValue(std::string &out, const Value &v) {
+    switch (v.GetType()) {
+    case rapidjson::kNullType:
+        out.push_back(JsonTagNull);
+        break;
+    case rapidjson::kFalseType:
+        out.push_back(JsonTagFalse);
+        break;
+    case rapidjson::kTrueType:
+        out.push_back(JsonTagTrue);
+        break;
+    case rapidjson::kNumberType:
+        if (v.IsInt64()) {
+            int64_t n = v.GetInt64();
+            out.push_back(JsonTagInt64);
+            out.append((const char *)&n, sizeof(n));
+        } else if (v.IsUint64()) {
+            uint64_t n = v.GetUint64();
+            out.push_back(JsonTagUint64);
+            out.append((const char *)&n, sizeof(n));
+        } else {
+            double d = v.GetDouble();
+            out.push_back(JsonTagDouble);
+            out.append((const char *)&d, sizeof(d));
+        }
+        break;
+    case rapidjson::kStringType:
+        out.push_back(JsonTagString);
+        EncodeString(out, v);
+        break;
+    case rapidjson::kArrayType:
+        out.push_back(JsonTagArray);
+        EncodeSize(out, v.Size());
+        for (Value::ConstValueIterator itr = v.Begin(); itr != v.End(); ++itr) {
+            EncodeValue(out, *itr);
+        }
+        break;
+    case rapidjson::kObjectType:
+        if (IsRawNumber(v)) {
+            out.push_back(JsonTagRawNumber);
+            EncodeString(out, RawNumberText(v));
+            break;
+        }
+        out.push_back(JsonTagObject);
+        EncodeSize(out, v.MemberCount());
+        for (Value::ConstMemberIterator itr = v.MemberBegin(); itr != v.MemberEnd(); ++itr) {
+            EncodeString(out, itr->name);
+            EncodeValue(out, itr->value);
+        }
+        break;
+    }
+}
+
+// ValEncode flattens a whole tree into one malloced buffer, so Go can
+// convert it without a cgo call per node
+char *ValEncode(JsonVal value, size_t *length) {
+    std::string out;
+    EncodeValue(out, *(Value *)value);
+
+    char *buffer = (char *)malloc(out.size());
+    memcpy(buffer, out.data(), out.size());
+    *length = out.size();
+    return buffer;
+}
+
 void SetInt(JsonVal value, int num) {
     ((Value *)value)->SetInt(num);
 }
 void SetInt64(JsonVal value, int64_t num) {
     ((Value *)value)->SetInt64(num);
 }
+void SetUint64(JsonVal value, uint64_t num) {
+    ((Value *)value)->SetUint64(num);
+}
 void SetDouble(JsonVal value, double num) {
     ((Value *)value)->SetDouble(num);
 }
-void SetString(JsonDoc json, JsonVal value, const char *str) {
+void SetString(JsonDoc json, JsonVal value, const char *str, size_t length) {
     Document *doc = (Document *)json;
-    ((Value *)value)->SetString(str, doc->GetAllocator());
+    ((Value *)value)->SetString(rapidjson::StringRef(str, length), doc->GetAllocator());
+}
+void SetRawNumber(JsonDoc json, JsonVal value, const char *str, size_t length) {
+    Document *doc = (Document *)json;
+    Value name(rapidjson::StringRef(kRawNumberName));
+    Value text(str, length, doc->GetAllocator());
+
+    ((Value *)value)->SetObject().AddMember(name, text, doc->GetAllocator());
 }
 void SetBool(JsonVal value, int b) {
     ((Value *)value)->SetBool((bool)b);
@@ -199,6 +1031,15 @@ void ArrayAppend(JsonDoc json, JsonVal value, JsonVal v) {
 
     val->PushBack(*item, doc->GetAllocator());
 }
+// appends a null in place and returns it, so nested values can be built
+// without a temporary Value per element
+JsonVal ArrayAppendNull(JsonDoc json, JsonVal value) {
+    Value *val = (Value *)value;
+    Document *doc = (Document *)json;
+
+    val->PushBack(Value().Move(), doc->GetAllocator());
+    return (void *) &val->operator[](val->Size() - 1);
+}
 JsonVal InitObj(JsonVal value) {
     return (void *) &((Value *)value)->SetObject();
 }
@@ -210,15 +1051,24 @@ void AddMember(JsonDoc json, JsonVal value, JsonVal k, JsonVal v) {
 
     val->AddMember(*key, *item, doc->GetAllocator());
 }
-void AddStrMember(JsonDoc json, JsonVal value, const char *k, JsonVal v) {
+void AddStrMember(JsonDoc json, JsonVal value, const char *k, size_t length, JsonVal v) {
     Value *val = (Value *)value;
     Value *item = (Value *)v;
     Document *doc = (Document *)json;
     Value key;
-    SetString(json, &key, k);
+    SetString(json, &key, k, length);
 
     val->AddMember(key, *item, doc->GetAllocator());
 }
+JsonVal AddStrMemberNull(JsonDoc json, JsonVal value, const char *k, size_t length) {
+    Value *val = (Value *)value;
+    Document *doc = (Document *)json;
+    Value key;
+    SetString(json, &key, k, length);
+
+    val->AddMember(key, Value().Move(), doc->GetAllocator());
+    return (void *) &(val->MemberEnd() - 1)->value;
+}
 void CopyFrom(JsonDoc json, JsonVal value, JsonVal from) {
     Value *val = (Value *)value;
     Value *item = (Value *)from;
@@ -232,8 +1082,9 @@ void Swap(JsonVal value, JsonVal other) {
     val->Swap(*item);
 }
 
-void RemoveMember(JsonVal value, const char *k) {
-    ((Value *)value)->RemoveMember(k);
+void RemoveMember(JsonVal value, const char *k, size_t length) {
+    Value key(rapidjson::StringRef(k, length));
+    ((Value *)value)->RemoveMember(key);
 }
 
 void ArrayRemove(JsonVal value, int index) {
@@ -244,3 +1095,196 @@ void ArrayRemove(JsonVal value, int index) {
 void ArrayClear(JsonVal value) {
     ((Value *)value)->Clear();
 }
+
+// JSON pointers are passed around in normalized string form and parsed
+// again for each call
+char *PointerNormalize(const char *path, size_t length, int uriFragment, size_t *outLength, int *errCode, size_t *errOffset) {
+    Pointer p(path ? path : "", length);
+    *errCode = p.GetParseErrorCode();
+    *errOffset = p.GetParseErrorOffset();
+    if (!p.IsValid()) {
+        *outLength = 0;
+        return NULL;
+    }
+
+    rapidjson::StringBuffer buffer;
+    bool ok = uriFragment ? p.StringifyUriFragment(buffer) : p.Stringify(buffer);
+
+    return BufferCopy(buffer, ok, outLength);
+}
+
+// RawNumberOnPath returns the lossless number p would descend into, or NULL.
+// Lossless numbers are leaves for pointers, their object is hidden
+static Value *RawNumberOnPath(const Pointer &p, Value &root) {
+    Value *v = &root;
+    for (size_t i = 0; i < p.GetTokenCount(); i++) {
+        const Pointer::Token &t = p.GetTokens()[i];
+        if (IsRawNumber(*v)) {
+            return v;
+        } else if (v->IsObject()) {
+            Value::MemberIterator m = v->FindMember(Value(rapidjson::StringRef(t.name, t.length)));
+            if (m == v->MemberEnd()) {
+                return NULL;
+            }
+            v = &m->value;
+        } else if (v->IsArray() && t.index != rapidjson::kPointerInvalidIndex && t.index < v->Size()) {
+            v = &(*v)[t.index];
+        } else {
+            return NULL;
+        }
+    }
+    return NULL;
+}
+// NullRawNumberOnPath nulls a lossless number on the path, so creating the path
+// replaces it as it would any other number
+static void NullRawNumberOnPath(const Pointer &p, Value &root) {
+    if (Value *raw = RawNumberOnPath(p, root)) {
+        raw->SetNull();
+    }
+}
+
+JsonVal PointerGet(JsonVal value, const char *path, size_t length) {
+    Pointer p(path ? path : "", length);
+    if (RawNumberOnPath(p, *(Value *)value)) {
+        return NULL;
+    }
+
+    return (void *) p.Get(*(Value *)value);
+}
+
+JsonVal PointerCreate(JsonDoc json, JsonVal value, const char *path, size_t length) {
+    Pointer p(path ? path : "", length);
+    Document *doc = (Document *)json;
+    NullRawNumberOnPath(p, *(Value *)value);
+
+    return (void *) &p.Create(*(Value *)value, doc->GetAllocator());
+}
+
+void PointerSet(JsonDoc json, JsonVal value, const char *path, size_t length, JsonVal item) {
+    Pointer p(path ? path : "", length);
+    Document *doc = (Document *)json;
+    NullRawNumberOnPath(p, *(Value *)value);
+
+    p.Set(*(Value *)value, *(Value *)item, doc->GetAllocator());
+}
+
+void PointerSwap(JsonDoc json, JsonVal value, const char *path, size_t length, JsonVal item) {
+    Pointer p(path ? path : "", length);
+    Document *doc = (Document *)json;
+    NullRawNumberOnPath(p, *(Value *)value);
+
+    p.Swap(*(Value *)value, *(Value *)item, doc->GetAllocator());
+}
+
+int PointerErase(JsonVal value, const char *path, size_t length) {
+    Pointer p(path ? path : "", length);
+    if (RawNumberOnPath(p, *(Value *)value)) {
+        return 0;
+    }
+
+    return p.Erase(*(Value *)value);
+}
+
+// PlainGenerator is the Populate callback of PlainValue
+class PlainGenerator {
+public:
+    PlainGenerator(const Value &value) : value_(value) {}
+    bool operator()(Document &doc) const { return AcceptValue(value_, doc); }
+
+private:
+    const Value &value_;
+};
+
+// PlainValue fills plain with a copy of value where lossless numbers are
+// plain numbers, for rapidjson code that doesn't know them such as the
+// schema compiler
+static const Value &PlainValue(const Value &value, Document &plain) {
+    PlainGenerator generator(value);
+    plain.Populate(generator);
+    return plain;
+}
+
+// JsonSchemaProvider resolves remote $ref documents through a Go loader,
+// compiling each document once and keeping it for the root schema lifetime
+class JsonSchemaProvider : public SchemaDocument::IRemoteSchemaDocumentProviderType {
+public:
+    JsonSchemaProvider(uintptr_t loader) : loader_(loader) {}
+    ~JsonSchemaProvider() {
+        for (std::map<std::string, SchemaDocument *>::iterator it = docs_.begin(); it != docs_.end(); ++it) {
+            delete it->second;
+        }
+    }
+
+    const SchemaDocument *GetRemoteDocument(const char *uri, rapidjson::SizeType length) {
+        std::string key(uri, length);
+        std::map<std::string, SchemaDocument *>::iterator it = docs_.find(key);
+        if (it != docs_.end()) {
+            return it->second;
+        }
+
+        JsonDoc doc = goLoadSchema(loader_, (char *)uri, length);
+        if (doc == NULL) {
+            return NULL;
+        }
+        JsonDocument plain(JsonAllocatorCrt, 0);
+        SchemaDocument *schema = new SchemaDocument(PlainValue(*(Value *)doc, plain), this);
+        docs_[key] = schema;
+        return schema;
+    }
+
+private:
+    uintptr_t loader_;
+    std::map<std::string, SchemaDocument *> docs_;
+};
+
+// JsonSchemaDocument is the root schema, the provider base is constructed
+// before and destroyed after the schema that references its documents
+class JsonSchemaDocument : public JsonSchemaProvider, public SchemaDocument {
+public:
+    JsonSchemaDocument(const Value &value, uintptr_t loader)
+        : JsonSchemaProvider(loader), SchemaDocument(value, loader ? this : NULL) {}
+};
+
+JsonSchema SchemaInit(JsonVal value, uintptr_t loader) {
+    JsonDocument plain(JsonAllocatorCrt, 0);
+    JsonSchemaDocument *schema = new JsonSchemaDocument(PlainValue(*(Value *)value, plain), loader);
+
+    return (void *)static_cast<SchemaDocument *>(schema);
+}
+
+void SchemaFree(JsonSchema schema) {
+    delete static_cast<JsonSchemaDocument *>((SchemaDocument *)schema);
+}
+
+static char *PointerString(const Pointer &p, size_t *length) {
+    rapidjson::StringBuffer buffer;
+    bool ok = p.Stringify(buffer);
+
+    return BufferCopy(buffer, ok, length);
+}
+
+int SchemaValidate(JsonSchema schema, JsonVal value, JsonValidationError *err) {
+    SchemaValidator validator(*(SchemaDocument *)schema);
+    if (AcceptValue(*(Value *)value, validator)) {
+        return 1;
+    }
+
+    err->schemaPointer = PointerString(validator.GetInvalidSchemaPointer(), &err->schemaPointerLength);
+    err->keyword = validator.GetInvalidSchemaKeyword();
+    err->documentPointer = PointerString(validator.GetInvalidDocumentPointer(), &err->documentPointerLength);
+    return 0;
+}
+
+int JsonParseValidated(JsonDoc json, const char *input, size_t length, unsigned flags, JsonSchema schema, JsonValidationError *err) {
+    ChunkStream is(input, length);
+    SchemaValidator validator(*(SchemaDocument *)schema);
+    ParseDispatch<rapidjson::kParseDefaultFlags>::Parse(ToJsonDocument(json), is, &validator, flags);
+    if (validator.IsValid()) {
+        return 1;
+    }
+
+    err->schemaPointer = PointerString(validator.GetInvalidSchemaPointer(), &err->schemaPointerLength);
+    err->keyword = validator.GetInvalidSchemaKeyword();
+    err->documentPointer = PointerString(validator.GetInvalidDocumentPointer(), &err->documentPointerLength);
+    return 0;
+}
@@ -1,71 +1,175 @@
 #ifndef __RJ_WRAPPER_H
 #define __RJ_WRAPPER_H
 
+#include <stddef.h>
+#include <stdint.h>
+
 #ifdef __cplusplus
 extern "C" {
 #endif
 
     typedef void* JsonDoc;
     typedef void* JsonVal;
+    typedef void* JsonSchema;
+
+    typedef struct {
+        int pretty;
+        char indentChar;
+        unsigned indentCount;
+        int singleLineArray;
+        int maxDecimalPlaces;
+        int nanAndInf;
+    } JsonWriteOptions;
+
+    typedef struct {
+        char *schemaPointer;
+        size_t schemaPointerLength;
+        const char *keyword;
+        char *documentPointer;
+        size_t documentPointerLength;
+    } JsonValidationError;
+
+    // implemented in Go, reads the next chunk of a reader into the buffer
+    extern size_t goReadChunk(uintptr_t, char *, size_t);
+    // implemented in Go, writes a chunk of output to a writer
+    extern int goWriteChunk(uintptr_t, char *, size_t);
+    // implemented in Go, loads and parses the schema document at a $ref URI
+    extern JsonDoc goLoadSchema(uintptr_t, char *, size_t);
+
+    // value tags of the ValEncode format. Every value is a tag byte, then
+    // 8 native endian bytes for numbers, a uint32 length and the bytes for
+    // strings and lossless number text, or a uint32 count and the elements
+    // for arrays. Objects are a uint32 count and that many pairs of string
+    // payload and value
+    enum {
+        JsonTagNull = 0,
+        JsonTagFalse,
+        JsonTagTrue,
+        JsonTagInt64,
+        JsonTagUint64,
+        JsonTagDouble,
+        JsonTagString,
+        JsonTagArray,
+        JsonTagObject,
+        JsonTagRawNumber
+    };
+
+    // number kinds returned by GetNumberKind, from narrowest to widest
+    enum {
+        JsonNumberNone = 0,
+        JsonNumberInt32,
+        JsonNumberUint32,
+        JsonNumberInt64,
+        JsonNumberUint64,
+        JsonNumberDouble
+    };
+
+    // parse flags, values match rapidjson::ParseFlag
+    enum {
+        JsonParseValidateEncoding = 2,
+        JsonParseIterative = 4,
+        JsonParseFullPrecision = 16,
+        JsonParseComments = 32,
+        JsonParseNumbersAsStrings = 64,
+        JsonParseTrailingCommas = 128,
+        JsonParseNanAndInf = 256
+    };
+
+    // allocators of JsonInitAllocator
+    enum {
+        JsonAllocatorCrt = 0,
+        JsonAllocatorPool
+    };
+
     JsonDoc JsonInit(void);
+    JsonDoc JsonInitAllocator(int, size_t);
+    void JsonReset(JsonDoc);
+    void JsonMemoryStats(JsonDoc, size_t *, size_t *);
     void JsonFree(JsonDoc);
     JsonVal ValInit(void);
     void ValFree(JsonVal);
 
-    void JsonParse(JsonDoc, char *);
+    void JsonParse(JsonDoc, const char *, size_t);
+    void JsonParseFlags(JsonDoc, const char *, size_t, unsigned);
+    void JsonParseReader(JsonDoc, uintptr_t, char *, size_t, unsigned);
     int HasParseError(JsonDoc);
     int GetParseErrorCode(JsonDoc);
     int64_t GetParseErrorOffset(JsonDoc);
 
     int IsValEqual(JsonVal, JsonVal);
 
-    char *GetString(JsonDoc);
-    char *GetPrettyString(JsonDoc);
+    char *GetString(JsonDoc, size_t *);
+    char *GetPrettyString(JsonDoc, size_t *);
+    int ValWrite(JsonVal, uintptr_t, char *, size_t, const JsonWriteOptions *);
 
-    int HasMember(JsonVal, const char *); 
+    int HasMember(JsonVal, const char *, size_t);
     int GetMemberCount(JsonVal);
-    char *GetMemberName(JsonVal, int);
+    const char *GetMemberName(JsonVal, int, size_t *);
 
-    JsonVal GetMember(JsonVal, const char *);
+    JsonVal GetMember(JsonVal, const char *, size_t);
     int GetType(JsonVal);
     int IsObj(JsonVal);
     int IsInt(JsonVal);
     int IsInt64(JsonVal);
+    int IsUint(JsonVal);
+    int IsUint64(JsonVal);
     int IsDouble(JsonVal);
+    int IsLosslessDouble(JsonVal);
+    int GetNumberKind(JsonVal);
     int IsBool(JsonVal);
     int IsString(JsonVal);
     int IsArray(JsonVal);
     int IsNull(JsonVal);
-    char *ValGetString(JsonVal);
-    char *ValGetPrettyString(JsonVal);
+    char *ValGetString(JsonVal, size_t *);
+    char *ValGetPrettyString(JsonVal, size_t *);
     int ValGetInt(JsonVal);
     int64_t ValGetInt64(JsonVal);
+    unsigned ValGetUint(JsonVal);
+    uint64_t ValGetUint64(JsonVal);
     double ValGetDouble(JsonVal);
     int ValGetBool(JsonVal);
-    char *ValGetBasicString(JsonVal);
+    const char *ValGetRawNumber(JsonVal, size_t *);
+    const char *ValGetBasicString(JsonVal, size_t *);
 
     int ValArraySize(JsonVal);
     JsonVal GetArrayValueAt(JsonVal, int);
+    char *ValEncode(JsonVal, size_t *);
 
     void SetInt(JsonVal, int);
     void SetInt64(JsonVal, int64_t);
+    void SetUint64(JsonVal, uint64_t);
     void SetDouble(JsonVal, double);
-    void SetString(JsonDoc, JsonVal, const char *);
+    void SetString(JsonDoc, JsonVal, const char *, size_t);
+    void SetRawNumber(JsonDoc, JsonVal, const char *, size_t);
     void SetBool(JsonVal, int);
     void SetNull(JsonVal);
     void SetValue(JsonVal, JsonVal);
     void InitArray(JsonVal);
     void ArrayAppend(JsonDoc, JsonVal, JsonVal);
+    JsonVal ArrayAppendNull(JsonDoc, JsonVal);
     JsonVal InitObj(JsonVal);
     void AddMember(JsonDoc, JsonVal, JsonVal, JsonVal);
-    void AddStrMember(JsonDoc, JsonVal, const char *, JsonVal);
+    void AddStrMember(JsonDoc, JsonVal, const char *, size_t, JsonVal);
+    JsonVal AddStrMemberNull(JsonDoc, JsonVal, const char *, size_t);
     void CopyFrom(JsonDoc, JsonVal, JsonVal);
     void Swap(JsonVal, JsonVal);
 
-    void RemoveMember(JsonVal, const char *);
+    void RemoveMember(JsonVal, const char *, size_t);
     void ArrayRemove(JsonVal, int);
     void ArrayClear(JsonVal);
 
+    char *PointerNormalize(const char *, size_t, int, size_t *, int *, size_t *);
+    JsonVal PointerGet(JsonVal, const char *, size_t);
+    JsonVal PointerCreate(JsonDoc, JsonVal, const char *, size_t);
+    void PointerSet(JsonDoc, JsonVal, const char *, size_t, JsonVal);
+    void PointerSwap(JsonDoc, JsonVal, const char *, size_t, JsonVal);
+    int PointerErase(JsonVal, const char *, size_t);
+
+    JsonSchema SchemaInit(JsonVal, uintptr_t);
+    void SchemaFree(JsonSchema);
+    int SchemaValidate(JsonSchema, JsonVal, JsonValidationError *);
+    int JsonParseValidated(JsonDoc, const char *, size_t, unsigned, JsonSchema, JsonValidationError *);
+
 #ifdef __cplusplus
 }
 #endif
@@ -0,0 +1,222 @@
+package rapidjson
+
+// #include <stdlib.h>
+// #include <stdint.h>
+// #include "rjwrapper.h"
+import "C"
+import "unsafe"
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"runtime/cgo"
+)
+
+var (
+	ErrSchemaInvalid = errors.New("JSON schema validation error")
+	ErrSchemaRef     = errors.New("Unresolved JSON schema reference")
+)
+
+// Schema is a JSON schema compiled from a Doc. The Doc is not referenced
+// after compiling and can be freed. Schema should be freed manually
+type Schema struct {
+	schema C.JsonSchema
+}
+
+// ValidationError is returned when a value doesn't match its schema,
+// errors.Is(err, ErrSchemaInvalid) holds for every ValidationError
+type ValidationError struct {
+	SchemaPointer   Pointer // the schema rule that failed
+	Keyword         string  // the failing keyword of that rule, e.g. "required"
+	DocumentPointer Pointer // the offending value in the validated document
+	Offset          int     // input offset where parsing stopped, only set by ParseValidated
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %q failed at schema %s for document %s",
+		ErrSchemaInvalid.Error(), e.Keyword, e.SchemaPointer.URIFragment(), e.DocumentPointer.URIFragment())
+}
+func (e *ValidationError) Unwrap() error {
+	return ErrSchemaInvalid
+}
+
+func newValidationError(cErr *C.JsonValidationError) *ValidationError {
+	defer C.free(unsafe.Pointer(cErr.schemaPointer))
+	defer C.free(unsafe.Pointer(cErr.documentPointer))
+	return &ValidationError{
+		SchemaPointer:   Pointer{path: stringFromC(cErr.schemaPointer, cErr.schemaPointerLength)},
+		Keyword:         C.GoString(cErr.keyword),
+		DocumentPointer: Pointer{path: stringFromC(cErr.documentPointer, cErr.documentPointerLength)},
+	}
+}
+
+// SchemaProvider loads the documents that remote $ref URIs point at, e.g.
+// "common.json" for {"$ref": "common.json#/definitions/id"}
+type SchemaProvider interface {
+	LoadSchema(uri string) ([]byte, error)
+}
+
+// fsProvider resolves $ref URIs as slash separated paths in a file system
+type fsProvider struct {
+	fsys fs.FS
+}
+
+func (p fsProvider) LoadSchema(uri string) ([]byte, error) {
+	return fs.ReadFile(p.fsys, path.Clean(uri))
+}
+
+// NewFSProvider resolves $ref URIs relative to the root of fsys
+func NewFSProvider(fsys fs.FS) SchemaProvider {
+	return fsProvider{fsys: fsys}
+}
+
+// NewDirProvider resolves $ref URIs relative to dir
+func NewDirProvider(dir string) SchemaProvider {
+	return fsProvider{fsys: os.DirFS(dir)}
+}
+
+// schemaLoader holds the documents loaded while compiling a schema, rapidjson
+// asks for each URI once unless the documents reference each other
+type schemaLoader struct {
+	provider SchemaProvider
+	loading  map[string]bool
+	docs     []*Doc
+	err      error
+}
+
+//export goLoadSchema
+func goLoadSchema(handle C.uintptr_t, uri *C.char, length C.size_t) C.JsonDoc {
+	loader := cgo.Handle(handle).Value().(*schemaLoader)
+	if loader.err != nil {
+		return nil
+	}
+	name := stringFromC(uri, length)
+	if loader.loading[name] {
+		loader.err = fmt.Errorf("%w %q: circular reference", ErrSchemaRef, name)
+		return nil
+	}
+	loader.loading[name] = true
+
+	input, err := loader.provider.LoadSchema(name)
+	if err != nil {
+		loader.err = fmt.Errorf("%w %q: %w", ErrSchemaRef, name, err)
+		return nil
+	}
+	doc := NewDoc()
+	loader.docs = append(loader.docs, doc)
+	err = doc.Parse(input)
+	if err == nil && doc.GetContainer().GetType() != TypeObject {
+		err = ErrNotObject
+	}
+	if err != nil {
+		loader.err = fmt.Errorf("%w %q: %w", ErrSchemaRef, name, err)
+		return nil
+	}
+	return doc.json
+}
+
+// initialization
+func NewSchema(doc *Doc) (*Schema, error) {
+	return NewSchemaWithProvider(doc, nil)
+}
+
+// NewSchemaWithProvider resolves remote $ref URIs through provider. Each
+// referenced document is loaded and compiled once, and kept until the
+// Schema is freed
+func NewSchemaWithProvider(doc *Doc, provider SchemaProvider) (*Schema, error) {
+	defer runtime.KeepAlive(doc)
+	if doc == nil {
+		return nil, ErrPathNotFound
+	}
+	if doc.GetContainer().GetType() != TypeObject {
+		return nil, ErrNotObject
+	}
+	var schema Schema
+	if provider == nil {
+		schema.schema = C.SchemaInit(C.JsonVal(unsafe.Pointer(doc.json)), 0)
+		return &schema, nil
+	}
+
+	loader := &schemaLoader{provider: provider, loading: map[string]bool{}}
+	handle := cgo.NewHandle(loader)
+	defer handle.Delete()
+	schema.schema = C.SchemaInit(C.JsonVal(unsafe.Pointer(doc.json)), C.uintptr_t(handle))
+	for _, loaded := range loader.docs {
+		loaded.Free()
+	}
+	if loader.err != nil {
+		schema.Free()
+		return nil, loader.err
+	}
+	return &schema, nil
+}
+func NewParsedStringSchema(input string) (*Schema, error) {
+	doc, err := NewParsedStringJson(input)
+	defer doc.Free()
+	if err != nil {
+		return nil, err
+	}
+	return NewSchema(doc)
+}
+
+// Free releases the schema, freeing twice is a no-op
+func (schema *Schema) Free() {
+	if schema == nil || schema.schema == nil {
+		return
+	}
+	C.SchemaFree(schema.schema)
+	schema.schema = nil
+}
+
+// validation
+func (schema *Schema) Validate(ct *Container) error {
+	defer runtime.KeepAlive(ct)
+	if schema == nil || schema.schema == nil || ct == nil {
+		return ErrPathNotFound
+	} else if ct.stale() {
+		return ErrStaleContainer
+	}
+	var cErr C.JsonValidationError
+	if CBoolTest(C.SchemaValidate(schema.schema, ct.ct, &cErr)) {
+		return nil
+	}
+	return newValidationError(&cErr)
+}
+
+// validating parse, the Doc is left unchanged if the input is invalid
+func (json *Doc) ParseValidated(input []byte, schema *Schema) error {
+	return json.ParseValidatedWithOptions(input, schema, ParseOptions{})
+}
+func (json *Doc) ParseStringValidated(input string, schema *Schema) error {
+	return json.ParseStringValidatedWithOptions(input, schema, ParseOptions{})
+}
+func (json *Doc) ParseValidatedWithOptions(input []byte, schema *Schema, opts ParseOptions) error {
+	cStr, size := bytesToC(input)
+	return json.parseValidated(cStr, size, schema, opts, func() string { return string(input) })
+}
+func (json *Doc) ParseStringValidatedWithOptions(input string, schema *Schema, opts ParseOptions) error {
+	cStr, size := stringToC(input)
+	return json.parseValidated(cStr, size, schema, opts, func() string { return input })
+}
+func (json *Doc) parseValidated(cStr *C.char, size C.size_t, schema *Schema, opts ParseOptions, input func() string) error {
+	defer runtime.KeepAlive(json)
+	if schema == nil || schema.schema == nil {
+		return ErrPathNotFound
+	}
+	var cErr C.JsonValidationError
+	if !CBoolTest(C.JsonParseValidated(json.json, cStr, size, opts.flags(), schema.schema, &cErr)) {
+		err := newValidationError(&cErr)
+		err.Offset = int(C.GetParseErrorOffset(json.json))
+		return err
+	}
+	return json.parseResult(input)
+}
+func NewParsedStringJsonValidated(input string, schema *Schema) (*Doc, error) {
+	doc := NewDoc()
+	err := doc.ParseStringValidated(input, schema)
+	return doc, err
+}
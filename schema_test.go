@@ -0,0 +1,197 @@
+package rapidjson
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+var (
+	testSchema1 = `{
+        "type": "object",
+        "properties": {
+            "member1": {"type": "integer", "minimum": 0},
+            "member2": {"type": "array", "items": {"type": "integer"}},
+            "member3": {
+                "type": "object",
+                "properties": {
+                    "sub1": {"type": "number"},
+                    "sub2": {"type": "boolean"}
+                },
+                "required": ["sub1"]
+            },
+            "member4": {"type": "string", "maxLength": 32}
+        },
+        "required": ["member1", "member4"]
+    }`
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schemaDoc, err := NewParsedStringJson(testSchema1)
+	assert.Nil(t, err, "should not error on parsing schema")
+	schema, err := NewSchema(schemaDoc)
+	assert.Nil(t, err, "should not error on compiling schema")
+	defer schema.Free()
+	// schema doesn't reference its source doc after compiling
+	schemaDoc.Free()
+
+	json, err := NewParsedStringJson(testJSON1)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+	assert.Nil(t, schema.Validate(ct))
+
+	ct.GetMemberOrNil("member2").ArrayAppend("six")
+	err = schema.Validate(ct)
+	assert.True(t, errors.Is(err, ErrSchemaInvalid), "should error on string in integer array")
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr), "should be a *ValidationError")
+	assert.Equal(t, "type", validationErr.Keyword)
+	assert.Equal(t, "/properties/member2/items", validationErr.SchemaPointer.String())
+	assert.Equal(t, "/member2/5", validationErr.DocumentPointer.String())
+	assert.Equal(t, `JSON schema validation error: "type" failed at schema #/properties/member2/items for document #/member2/5`, err.Error())
+
+	ct.GetMemberOrNil("member2").ArrayRemove(5)
+	ct.GetMemberOrNil("member3").RemoveMember("sub1")
+	err = schema.Validate(ct)
+	assert.True(t, errors.As(err, &validationErr), "should error on missing required member")
+	assert.Equal(t, "required", validationErr.Keyword)
+	assert.Equal(t, "/member3", validationErr.DocumentPointer.String())
+
+	// validate a sub container
+	sub, err := NewParsedStringSchema(`{"type": "integer", "maximum": 10}`)
+	assert.Nil(t, err, "should not error on compiling schema")
+	defer sub.Free()
+	err = sub.Validate(ct.GetMemberOrNil("member1"))
+	assert.True(t, errors.As(err, &validationErr), "should error on maximum")
+	assert.Equal(t, "maximum", validationErr.Keyword)
+	assert.Equal(t, "", validationErr.DocumentPointer.String())
+
+	// freeing twice is a no-op, a freed schema validates nothing
+	freed, _ := NewParsedStringSchema(`{"type": "integer"}`)
+	freed.Free()
+	freed.Free()
+	assert.Equal(t, ErrPathNotFound, freed.Validate(ct))
+	assert.Equal(t, ErrPathNotFound, json.ParseStringValidated(`1`, freed))
+}
+
+func TestParseValidated(t *testing.T) {
+	schema, err := NewParsedStringSchema(testSchema1)
+	assert.Nil(t, err, "should not error on compiling schema")
+	defer schema.Free()
+
+	json, err := NewParsedStringJsonValidated(testJSON1, schema)
+	assert.Nil(t, err, "should not error on valid input")
+	defer json.Free()
+	expected := `{"member1":12345,"member2":[1,2,3,4,5],"member3":{"sub1":1.234,"sub2":true,"sub3":null},"member4":"rapidjson is awesome!"}`
+	assert.Equal(t, expected, json.String())
+
+	input := `{"member1": 1, "member2": [1, "two", 3], "member4": "x"}`
+	err = json.ParseStringValidated(input, schema)
+	assert.True(t, errors.Is(err, ErrSchemaInvalid), "should error on string in integer array")
+	assert.False(t, errors.Is(err, ErrJsonParse), "should not be a parse error")
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr), "should be a *ValidationError")
+	assert.Equal(t, "type", validationErr.Keyword)
+	assert.Equal(t, "/member2/1", validationErr.DocumentPointer.String())
+	assert.Equal(t, strings.Index(input, `"two"`)+len(`"two"`), validationErr.Offset)
+	// stops before building the DOM, so the previous content is kept
+	assert.Equal(t, expected, json.String())
+
+	err = json.ParseValidated([]byte(`{"member1": 1, "member4": "x"`), schema)
+	assert.True(t, errors.Is(err, ErrJsonParse), "should error on bad json")
+
+	err = json.ParseValidatedWithOptions([]byte(`{"member1": 1, /* comment */ "member4": "x",}`), schema, ParseOptions{Comments: true, TrailingCommas: true})
+	assert.Nil(t, err, "should not error with options")
+	assert.Equal(t, `{"member1":1,"member4":"x"}`, json.String())
+}
+
+func TestSchemaProvider(t *testing.T) {
+	fsys := fstest.MapFS{
+		"common.json": {Data: []byte(`{"definitions": {
+            "id": {"type": "integer", "minimum": 1},
+            "person": {"properties": {"name": {"$ref": "types/string.json#/definitions/short"}}}
+        }}`)},
+		"types/string.json": {Data: []byte(`{"definitions": {"short": {"type": "string", "maxLength": 8}}}`)},
+		"a.json":            {Data: []byte(`{"properties": {"b": {"$ref": "b.json#"}}}`)},
+		"b.json":            {Data: []byte(`{"properties": {"a": {"$ref": "a.json#"}}}`)},
+		"list.json":         {Data: []byte(`[]`)},
+	}
+	schemaDoc, err := NewParsedStringJson(`{
+        "type": "object",
+        "properties": {
+            "id": {"$ref": "common.json#/definitions/id"},
+            "parent": {"$ref": "./common.json#/definitions/id"},
+            "owner": {"$ref": "common.json#/definitions/person"}
+        }
+    }`)
+	assert.Nil(t, err, "should not error on parsing schema")
+	defer schemaDoc.Free()
+	schema, err := NewSchemaWithProvider(schemaDoc, NewFSProvider(fsys))
+	assert.Nil(t, err, "should not error on compiling schema")
+	defer schema.Free()
+
+	json, err := NewParsedStringJson(`{"id": 1, "parent": 2, "owner": {"name": "short"}}`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+	ct := json.GetContainer()
+	assert.Nil(t, schema.Validate(ct))
+
+	var validationErr *ValidationError
+	ct.GetMemberOrNil("parent").SetValue(0)
+	err = schema.Validate(ct)
+	assert.True(t, errors.As(err, &validationErr), "should error on minimum from referenced file")
+	assert.Equal(t, "minimum", validationErr.Keyword)
+	assert.Equal(t, "/parent", validationErr.DocumentPointer.String())
+
+	ct.GetMemberOrNil("parent").SetValue(2)
+	ct.GetMemberOrNil("owner").GetMemberOrNil("name").SetValue("much too long")
+	err = schema.Validate(ct)
+	assert.True(t, errors.As(err, &validationErr), "should error on maxLength from nested reference")
+	assert.Equal(t, "maxLength", validationErr.Keyword)
+
+	// directory provider
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "common.json"), fsys["common.json"].Data, 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "types"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "types", "string.json"), fsys["types/string.json"].Data, 0644))
+	dirSchema, err := NewSchemaWithProvider(schemaDoc, NewDirProvider(dir))
+	assert.Nil(t, err, "should not error on compiling schema from dir")
+	defer dirSchema.Free()
+	err = dirSchema.Validate(ct)
+	assert.True(t, errors.As(err, &validationErr), "should error on maxLength from dir")
+	assert.Equal(t, "maxLength", validationErr.Keyword)
+
+	// errors
+	load := func(input string) error {
+		doc, err := NewParsedStringJson(input)
+		assert.Nil(t, err, "should not error on parsing schema")
+		defer doc.Free()
+		schema, err := NewSchemaWithProvider(doc, NewFSProvider(fsys))
+		schema.Free()
+		return err
+	}
+	err = load(`{"$ref": "missing.json#"}`)
+	assert.True(t, errors.Is(err, ErrSchemaRef), "should error on missing file")
+	assert.True(t, errors.Is(err, fs.ErrNotExist), "should keep the provider error")
+	err = load(`{"$ref": "http://example.com/remote.json#"}`)
+	assert.True(t, errors.Is(err, ErrSchemaRef), "should not resolve network URIs")
+	err = load(`{"$ref": "a.json#"}`)
+	assert.True(t, errors.Is(err, ErrSchemaRef), "should error on circular reference")
+	err = load(`{"$ref": "list.json#"}`)
+	assert.True(t, errors.Is(err, ErrNotObject), "should error on non-object document")
+}
+
+func TestSchemaInit(t *testing.T) {
+	_, err := NewParsedStringSchema(`[1, 2]`)
+	assert.Equal(t, ErrNotObject, err)
+
+	_, err = NewParsedStringSchema(`{"type": `)
+	assert.True(t, errors.Is(err, ErrJsonParse), "should error on bad schema json")
+}
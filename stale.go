@@ -0,0 +1,80 @@
+package rapidjson
+
+// #include "rjwrapper.h"
+import "C"
+
+import "errors"
+
+var ErrStaleContainer = errors.New("Container is stale")
+
+// Stale checking, see DocOptions.CheckStale. The Doc stamps a value with its
+// generation clock whenever values below it may have been freed or moved,
+// and a Container remembers the stamp of the Container it was derived from.
+// Changes below a Container leave it valid, so a parent can be changed
+// through while its other Containers stay in use
+
+// stale tells whether ct may point at freed or moved memory. Containers of
+// a freed Doc are always stale
+func (ct *Container) stale() bool {
+	if ct == nil || ct.doc == nil {
+		return false
+	}
+	doc := ct.doc
+	if doc.json == nil {
+		return true
+	} else if !doc.checkStale {
+		return false
+	} else if ct.epoch != doc.epoch {
+		return true
+	}
+	for c := ct; c.parent != nil; c = c.parent {
+		if c.deep && doc.clock != c.stamp {
+			return true
+		} else if !c.deep && doc.changes[c.parent.ct] != c.stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// derive makes a Container for val, a child of ct
+func (ct *Container) derive(val C.JsonVal) *Container {
+	m := &Container{doc: ct.doc, ct: val}
+	if ct.doc != nil && ct.doc.checkStale {
+		m.parent = ct
+		m.stamp = ct.doc.changes[ct.ct]
+		m.epoch = ct.epoch
+	}
+	return m
+}
+
+// deriveDeep makes a Container for val, any value below ct such as the
+// target of a JSON pointer. The levels in between aren't known, so it goes
+// stale on any change to the Doc
+func (ct *Container) deriveDeep(val C.JsonVal) *Container {
+	m := ct.derive(val)
+	if m.parent != nil {
+		m.deep = true
+		m.stamp = ct.doc.clock
+	}
+	return m
+}
+
+// changed records that values below cts may have been freed or moved
+func changed(cts ...*Container) {
+	for _, ct := range cts {
+		if ct == nil || ct.doc == nil || !ct.doc.checkStale {
+			continue
+		}
+		ct.doc.clock++
+		ct.doc.changes[ct.ct] = ct.doc.clock
+	}
+}
+
+// newEpoch makes every Container of the Doc stale, for Reset
+func (json *Doc) newEpoch() {
+	if json.checkStale {
+		json.epoch++
+		json.changes = map[C.JsonVal]uint64{}
+	}
+}
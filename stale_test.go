@@ -0,0 +1,145 @@
+package rapidjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func testStaleDoc(t *testing.T, input string) *Doc {
+	json := NewDocWithOptions(DocOptions{CheckStale: true})
+	assert.Nil(t, json.ParseString(input), "should not error on parsing")
+	return json
+}
+
+func TestStaleContainer(t *testing.T) {
+	json := testStaleDoc(t, `{"a": {"b": 1}, "c": [1, 2, 3], "d": "x"}`)
+	defer json.Free()
+	ct := json.GetContainer()
+
+	// removing a member stales Containers below the object, not the object
+	b := ct.GetMemberOrNil("a").GetMemberOrNil("b")
+	a := ct.GetMemberOrNil("a")
+	c := ct.GetMemberOrNil("c")
+	assert.Nil(t, ct.RemoveMember("d"))
+	_, err := b.GetInt()
+	assert.Equal(t, ErrStaleContainer, err)
+	_, err = a.GetMemberCount()
+	assert.Equal(t, ErrStaleContainer, err)
+	assert.Equal(t, "", c.String())
+	assert.Equal(t, `{"a":{"b":1},"c":[1,2,3]}`, ct.String())
+
+	// changes below a Container leave it and its siblings valid
+	a = ct.GetMemberOrNil("a")
+	c = ct.GetMemberOrNil("c")
+	first := c.GetArrayValue(0)
+	assert.Nil(t, a.AddValue("e", true))
+	assert.Nil(t, c.ArrayAppend(4))
+	assert.Equal(t, `{"b":1,"e":true}`, a.String())
+	_, err = first.GetInt()
+	assert.Equal(t, ErrStaleContainer, err)
+	n, err := c.GetArrayValue(0).GetInt()
+	assert.Nil(t, err, "should not error on a fresh Container")
+	assert.Equal(t, 1, n)
+
+	// clearing and swapping
+	first = c.GetArrayValue(0)
+	assert.Nil(t, c.ArrayClear())
+	assert.Equal(t, ErrStaleContainer, first.SetValue(5))
+	e := a.GetMemberOrNil("e")
+	a.SwapContainer(c)
+	_, err = e.GetBool()
+	assert.Equal(t, ErrStaleContainer, err)
+	assert.Equal(t, `{"a":[],"c":{"b":1,"e":true}}`, ct.String())
+
+	// setters report stale Containers on either side
+	stale := ct.GetMemberOrNil("a")
+	fresh := json.NewContainer()
+	assert.Nil(t, ct.AddValue("f", 1))
+	assert.Equal(t, ErrStaleContainer, stale.SetMember("g", fresh))
+	assert.Equal(t, ErrStaleContainer, stale.SetMemberCopy("g", fresh))
+	assert.Equal(t, ErrStaleContainer, ct.SetMember("g", first))
+	assert.Equal(t, ErrStaleContainer, ct.SetMemberCopy("g", first))
+	assert.Equal(t, ErrStaleContainer, ct.AddMemberAtPath("g", first))
+	assert.Equal(t, `{"a":[],"c":{"b":1,"e":true},"f":1}`, ct.String())
+
+	// parsing again replaces the root
+	c = ct.GetMemberOrNil("c")
+	assert.Nil(t, json.ParseString(`{"c": 1}`))
+	assert.Equal(t, ErrStaleContainer, c.AddValue("f", 1))
+	assert.Equal(t, `{"c":1}`, ct.String())
+
+	// so does parsing from a reader
+	c = ct.GetMemberOrNil("c")
+	assert.Nil(t, json.ParseReader(strings.NewReader(`{"c": {"d": 2}}`)))
+	_, err = c.GetInt()
+	assert.Equal(t, ErrStaleContainer, err)
+	_, err = ct.GetMemberOrNil("c").GetMember("d")
+	assert.Nil(t, err, "should not error on a fresh Container")
+}
+
+func TestStaleBuilding(t *testing.T) {
+	json := NewDocWithOptions(DocOptions{CheckStale: true})
+	defer json.Free()
+	root := json.GetContainerNewObj()
+
+	// new Containers are independent of the Doc until added
+	item := json.NewContainerObj()
+	assert.Nil(t, item.AddValue("a", 1))
+	assert.Nil(t, item.AddValue("b", []int{1, 2}))
+	inner := item.GetMemberOrNil("b")
+	assert.Nil(t, root.AddMember("item", item))
+	assert.Nil(t, root.AddValue("n", 2))
+	assert.Equal(t, `{"item":{"a":1,"b":[1,2]},"n":2}`, json.String())
+	_, err := inner.GetArraySize()
+	assert.Equal(t, ErrStaleContainer, err, "moved with the item")
+
+	// pointer targets go stale on any change
+	p, _ := NewPointer("/item/b/0")
+	target, err := root.GetPointer(p)
+	assert.Nil(t, err, "should not error on pointer")
+	assert.Nil(t, root.GetMemberOrNil("item").RemoveMember("b"))
+	_, err = target.GetInt()
+	assert.Equal(t, ErrStaleContainer, err)
+
+	// StripNulls changes values below Containers it still walks
+	assert.Nil(t, root.AddValue("z", map[string]interface{}{"x": nil, "y": []interface{}{nil, 1}}))
+	root.StripNulls(false)
+	assert.Equal(t, `{"item":{"a":1},"n":2,"z":{"y":[1]}}`, json.String())
+}
+
+func TestStaleResetAndFree(t *testing.T) {
+	json := testStaleDoc(t, `{"a": 1}`)
+	a := json.GetContainer().GetMemberOrNil("a")
+	item := json.NewContainer()
+	json.Reset()
+	_, err := a.GetInt()
+	assert.Equal(t, ErrStaleContainer, err)
+	assert.Equal(t, ErrStaleContainer, item.SetValue(1))
+	assert.Nil(t, json.ParseString(`[1]`))
+	root := json.GetContainer()
+	json.Free()
+	_, err = root.GetArraySize()
+	assert.Equal(t, ErrStaleContainer, err)
+
+	// without CheckStale only a freed Doc is detected
+	plain, _ := NewParsedStringJson(`{"a": 1}`)
+	a = plain.GetContainer().GetMemberOrNil("a")
+	plain.Free()
+	_, _, err = a.AsInt()
+	assert.Equal(t, ErrStaleContainer, err)
+}
+
+func TestStaleCopies(t *testing.T) {
+	json := testStaleDoc(t, `{"a": {"x": [1, 2]}}`)
+	defer json.Free()
+	ct := json.GetContainer()
+
+	// items are copied before the members they'd go stale by
+	assert.Nil(t, ct.AddMemberCopy("b", ct.GetMemberOrNil("a")))
+	assert.Nil(t, ct.SetMemberCopy("c", ct.GetMemberOrNil("a")))
+	a := ct.GetMemberOrNil("a")
+	assert.Nil(t, a.SetMemberCopy("x", a.GetMemberOrNil("x").GetArrayValue(0)))
+	assert.Equal(t, `{"a":{"x":1},"b":{"x":[1,2]},"c":{"x":[1,2]}}`, json.String())
+}
@@ -0,0 +1,251 @@
+package rapidjson
+
+// #include <stdlib.h>
+// #include <stdint.h>
+// #include "rjwrapper.h"
+import "C"
+import "unsafe"
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"runtime/cgo"
+	"strings"
+)
+
+// size of the C buffer rapidjson parses from when reading a stream
+const readChunkSize = 64 * 1024
+
+// input kept from previous chunks, for the context of errors in tokens
+// spanning chunks
+const readKeep = 1024
+
+// size of the C buffer rapidjson writes to before handing output to Go
+const writeChunkSize = 16 * 1024
+
+// readSource feeds chunks of an io.Reader to rapidjson, and keeps enough
+// position info to build a ParseError without holding the whole input
+type readSource struct {
+	r     io.Reader
+	err   error
+	chunk []byte // current chunk, a view of the C buffer
+	start int    // offset of chunk in the input
+	lines int    // newlines before chunk
+	last  int    // offset of the last newline before chunk, -1 if none
+	prev  []byte // tail of the previous chunks, readKeep bytes at most
+}
+
+//export goReadChunk
+func goReadChunk(handle C.uintptr_t, buffer *C.char, size C.size_t) C.size_t {
+	src := cgo.Handle(handle).Value().(*readSource)
+	src.start += len(src.chunk)
+	src.lines += bytes.Count(src.chunk, []byte{'\n'})
+	if i := bytes.LastIndexByte(src.chunk, '\n'); i >= 0 {
+		src.last = src.start - len(src.chunk) + i
+	}
+	src.prev = append(src.prev, src.chunk[max(len(src.chunk)-readKeep, 0):]...)
+	if len(src.prev) > readKeep {
+		src.prev = src.prev[len(src.prev)-readKeep:]
+	}
+	src.chunk = nil
+
+	if src.err != nil {
+		return 0
+	}
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(buffer)), int(size))
+	for {
+		n, err := src.r.Read(buf)
+		if err != nil && err != io.EOF {
+			src.err = err
+		}
+		if n > 0 || err != nil {
+			src.chunk = buf[:n]
+			return C.size_t(n)
+		}
+	}
+}
+
+// writeSink receives chunks of writer output from rapidjson
+type writeSink struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+//export goWriteChunk
+func goWriteChunk(handle C.uintptr_t, buffer *C.char, size C.size_t) C.int {
+	sink := cgo.Handle(handle).Value().(*writeSink)
+	n, err := sink.w.Write(unsafe.Slice((*byte)(unsafe.Pointer(buffer)), int(size)))
+	sink.n += int64(n)
+	if err == nil && n < int(size) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		sink.err = err
+		return 1
+	}
+	return 0
+}
+
+// parseError locates offset in the input kept, the tail of the previous
+// chunks and the chunk. An offset before the chunk is the start of a token
+// spanning chunks, Context then has what's kept of it
+func (src *readSource) parseError(code ParseErrorCode, offset int) *ParseError {
+	kept := append(append([]byte{}, src.prev...), src.chunk...)
+	split := len(src.prev)
+	rel := min(max(offset-(src.start-split), 0), len(kept))
+
+	line := src.lines + 1
+	if rel < split {
+		line -= bytes.Count(kept[rel:split], []byte{'\n'})
+	} else {
+		line += bytes.Count(kept[split:rel], []byte{'\n'})
+	}
+	column := offset - src.last
+	if i := bytes.LastIndexByte(kept[:rel], '\n'); i >= 0 {
+		column = rel - i
+	}
+	return &ParseError{
+		Code:    code,
+		Offset:  offset,
+		Line:    line,
+		Column:  column,
+		Context: string(kept[max(rel-parseErrorContext, 0):min(rel+parseErrorContext, len(kept))]),
+	}
+}
+
+// parse from streams
+func (json *Doc) ParseReader(r io.Reader) error {
+	return json.ParseReaderWithOptions(r, ParseOptions{})
+}
+func (json *Doc) ParseReaderWithOptions(r io.Reader, opts ParseOptions) error {
+	defer runtime.KeepAlive(json)
+	src := &readSource{r: r, last: -1}
+	handle := cgo.NewHandle(src)
+	defer handle.Delete()
+	buffer := (*C.char)(C.malloc(readChunkSize))
+	defer C.free(unsafe.Pointer(buffer))
+
+	C.JsonParseReader(json.json, C.uintptr_t(handle), buffer, readChunkSize, opts.flags())
+	// the root was replaced
+	changed(json.GetContainer())
+
+	if src.err != nil {
+		return src.err
+	} else if json.HasParseError() {
+		errCode := ParseErrorCode(C.GetParseErrorCode(json.json))
+		errOffset := int(C.GetParseErrorOffset(json.json))
+		return src.parseError(errCode, errOffset)
+	} else {
+		return nil
+	}
+}
+func (json *Doc) ParseFile(path string) error {
+	return json.ParseFileWithOptions(path, ParseOptions{})
+}
+func (json *Doc) ParseFileWithOptions(path string, opts ParseOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.ParseReaderWithOptions(f, opts)
+}
+func NewParsedReader(r io.Reader) (*Doc, error) {
+	doc := NewDoc()
+	err := doc.ParseReader(r)
+	return doc, err
+}
+func NewParsedFile(path string) (*Doc, error) {
+	doc := NewDoc()
+	err := doc.ParseFile(path)
+	return doc, err
+}
+
+// WriteOptions configure serialization, the zero value writes compact
+// output like String()
+type WriteOptions struct {
+	Pretty           bool   // multi-line output, like Pretty()
+	Indent           string // one repeated space, tab, CR or LF, defaults to 4 spaces
+	SingleLineArray  bool   // keep arrays on one line in pretty output
+	MaxDecimalPlaces int    // truncate floats to this many decimals, 0 keeps full precision
+	NanAndInf        bool   // write NaN, Infinity and -Infinity instead of failing
+}
+
+func (opts WriteOptions) toC() (C.JsonWriteOptions, error) {
+	var cOpts C.JsonWriteOptions
+	cOpts.pretty = BoolToC(opts.Pretty)
+	cOpts.indentChar = ' '
+	cOpts.indentCount = 4
+	if opts.Indent != "" {
+		c := opts.Indent[0]
+		if strings.Count(opts.Indent, string(c)) != len(opts.Indent) || strings.IndexByte(" \t\r\n", c) < 0 {
+			return cOpts, ErrBadIndent
+		}
+		cOpts.indentChar = C.char(c)
+		cOpts.indentCount = C.unsigned(len(opts.Indent))
+	}
+	cOpts.singleLineArray = BoolToC(opts.SingleLineArray)
+	if opts.MaxDecimalPlaces < 0 {
+		return cOpts, ErrBadDecimalPlaces
+	}
+	cOpts.maxDecimalPlaces = C.int(opts.MaxDecimalPlaces)
+	cOpts.nanAndInf = BoolToC(opts.NanAndInf)
+	return cOpts, nil
+}
+
+// write to streams
+func (json *Doc) WriteTo(w io.Writer) (int64, error) {
+	return json.GetContainer().WriteTo(w)
+}
+func (json *Doc) WritePrettyTo(w io.Writer) (int64, error) {
+	return json.GetContainer().WritePrettyTo(w)
+}
+func (json *Doc) FormatTo(w io.Writer, opts WriteOptions) (int64, error) {
+	return json.GetContainer().FormatTo(w, opts)
+}
+func (json *Doc) Format(opts WriteOptions) (string, error) {
+	return json.GetContainer().Format(opts)
+}
+func (ct *Container) WriteTo(w io.Writer) (int64, error) {
+	return ct.FormatTo(w, WriteOptions{})
+}
+func (ct *Container) WritePrettyTo(w io.Writer) (int64, error) {
+	return ct.FormatTo(w, WriteOptions{Pretty: true})
+}
+func (ct *Container) Format(opts WriteOptions) (string, error) {
+	var sb strings.Builder
+	_, err := ct.FormatTo(&sb, opts)
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+func (ct *Container) FormatTo(w io.Writer, opts WriteOptions) (int64, error) {
+	defer runtime.KeepAlive(ct)
+	if ct == nil {
+		return 0, ErrPathNotFound
+	} else if ct.stale() {
+		return 0, ErrStaleContainer
+	}
+	cOpts, err := opts.toC()
+	if err != nil {
+		return 0, err
+	}
+	sink := &writeSink{w: w}
+	handle := cgo.NewHandle(sink)
+	defer handle.Delete()
+	buffer := (*C.char)(C.malloc(writeChunkSize))
+	defer C.free(unsafe.Pointer(buffer))
+
+	ok := CBoolTest(C.ValWrite(ct.ct, C.uintptr_t(handle), buffer, writeChunkSize, &cOpts))
+	if sink.err != nil {
+		return sink.n, sink.err
+	} else if !ok {
+		return sink.n, ErrNanOrInf
+	} else {
+		return sink.n, nil
+	}
+}
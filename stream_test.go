@@ -0,0 +1,228 @@
+package rapidjson
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert" // Assertion package
+)
+
+func TestParseReader(t *testing.T) {
+	json, err := NewParsedReader(iotest.OneByteReader(strings.NewReader(testJSON1)))
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	expected := `{"member1":12345,"member2":[1,2,3,4,5],"member3":{"sub1":1.234,"sub2":true,"sub3":null},"member4":"rapidjson is awesome!"}`
+	assert.Equal(t, expected, json.String())
+
+	// spans several chunks
+	large := "[" + strings.Repeat(`"rapidjson",`, 20000) + "null]"
+	json2, err := NewParsedReader(strings.NewReader(large))
+	assert.Nil(t, err, "should not error on parsing large input")
+	defer json2.Free()
+	size, _ := json2.GetContainer().GetArraySize()
+	assert.Equal(t, 20001, size)
+
+	json3 := NewDoc()
+	defer json3.Free()
+	err = json3.ParseReaderWithOptions(strings.NewReader(`[1, 2, /* three */ 3,]`), ParseOptions{Comments: true, TrailingCommas: true})
+	assert.Nil(t, err, "should not error on parsing with options")
+	assert.Equal(t, `[1,2,3]`, json3.String())
+}
+
+func TestParseReaderError(t *testing.T) {
+	input := strings.Repeat("[1,\n", 30000) + "x" + strings.Repeat("]", 30000)
+	json := NewDoc()
+	defer json.Free()
+	err := json.ParseReader(strings.NewReader(input))
+	assert.True(t, errors.Is(err, ErrJsonParse), "should be a parse error")
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr), "should be a *ParseError")
+	assert.Equal(t, ParseErrorValueInvalid, parseErr.Code)
+	assert.Equal(t, strings.Index(input, "x"), parseErr.Offset)
+	assert.Equal(t, 30001, parseErr.Line)
+	assert.Equal(t, 1, parseErr.Column)
+	assert.Equal(t, input[parseErr.Offset-20:parseErr.Offset+20], parseErr.Context)
+
+	// tokens spanning chunks report the offset of their start
+	for _, input := range []string{
+		strings.Repeat(" ", 65531) + "[1e99999999]",
+		strings.Repeat("\n", 10) + strings.Repeat(" ", 65521) + "[1e99999999]",
+		strings.Repeat("[\n", 30000) + `"` + strings.Repeat("x", 70000) + `\q"`,
+	} {
+		want := json.ParseString(input)
+		err = json.ParseReader(strings.NewReader(input))
+		assert.Equal(t, want, err)
+
+		// input after the error is never read
+		err = json.ParseReader(iotest.OneByteReader(strings.NewReader(input)))
+		assert.True(t, errors.As(err, &parseErr), "should be a *ParseError")
+		context := parseErr.Context
+		parseErr.Context = want.(*ParseError).Context
+		assert.Equal(t, want, err)
+		assert.True(t, strings.HasPrefix(parseErr.Context, context))
+	}
+
+	readErr := errors.New("connection reset")
+	err = json.ParseReader(iotest.TimeoutReader(iotest.DataErrReader(strings.NewReader(`{"a":`))))
+	assert.NotNil(t, err, "should error on reader failure")
+	assert.False(t, errors.Is(err, ErrJsonParse), "should return the reader error")
+
+	err = json.ParseReader(iotest.ErrReader(readErr))
+	assert.Equal(t, readErr, err)
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.json")
+	assert.Nil(t, os.WriteFile(path, []byte(testJSON1), 0644))
+
+	json, err := NewParsedFile(path)
+	assert.Nil(t, err, "should not error on parsing file")
+	defer json.Free()
+	member4, err := json.GetContainer().GetMemberOrNil("member4").GetString()
+	assert.Nil(t, err, "should not error on member4")
+	assert.Equal(t, "rapidjson is awesome!", member4)
+
+	err = json.ParseFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "should error on missing file")
+}
+
+type limitWriter struct {
+	limit int
+	n     int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.n+len(p) > w.limit {
+		return 0, errors.New("limit reached")
+	}
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestWriteTo(t *testing.T) {
+	json, err := NewParsedStringJson(testJSON1)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	var buf bytes.Buffer
+	n, err := json.WriteTo(&buf)
+	assert.Nil(t, err, "should not error on write")
+	assert.Equal(t, json.String(), buf.String())
+	assert.Equal(t, int64(buf.Len()), n)
+
+	buf.Reset()
+	_, err = json.WritePrettyTo(&buf)
+	assert.Nil(t, err, "should not error on pretty write")
+	assert.Equal(t, json.Pretty(), buf.String())
+
+	buf.Reset()
+	member3 := json.GetContainer().GetMemberOrNil("member3")
+	_, err = member3.WriteTo(&buf)
+	assert.Nil(t, err, "should not error on container write")
+	assert.Equal(t, member3.String(), buf.String())
+
+	// spans several chunks
+	large := "[" + strings.Repeat(`"rapidjson",`, 20000) + "null]"
+	json2, err := NewParsedStringJson(large)
+	assert.Nil(t, err, "should not error on parsing large input")
+	defer json2.Free()
+	buf.Reset()
+	n, err = json2.WriteTo(&buf)
+	assert.Nil(t, err, "should not error on large write")
+	assert.Equal(t, large, buf.String())
+	assert.Equal(t, int64(len(large)), n)
+
+	w := &limitWriter{limit: 50000}
+	n, err = json2.WriteTo(w)
+	assert.EqualError(t, err, "limit reached")
+	assert.Equal(t, int64(w.n), n)
+}
+
+func TestFormat(t *testing.T) {
+	json, err := NewParsedStringJson(`{"a":[1,2,3],"b":{"c":3.14159265}}`)
+	assert.Nil(t, err, "should not error on parsing")
+	defer json.Free()
+
+	out, err := json.Format(WriteOptions{})
+	assert.Nil(t, err, "should not error on default format")
+	assert.Equal(t, json.String(), out)
+
+	out, err = json.Format(WriteOptions{Pretty: true})
+	assert.Nil(t, err, "should not error on pretty format")
+	assert.Equal(t, json.Pretty(), out)
+
+	out, err = json.Format(WriteOptions{Pretty: true, Indent: "  ", SingleLineArray: true, MaxDecimalPlaces: 2})
+	assert.Nil(t, err, "should not error on custom format")
+	assert.Equal(t, "{\n  \"a\": [1, 2, 3],\n  \"b\": {\n    \"c\": 3.14\n  }\n}", out)
+
+	out, err = json.GetContainer().GetMemberOrNil("b").Format(WriteOptions{Pretty: true, Indent: "\t"})
+	assert.Nil(t, err, "should not error on tab format")
+	assert.Equal(t, "{\n\t\"c\": 3.14159265\n}", out)
+
+	out, err = json.Format(WriteOptions{MaxDecimalPlaces: 3})
+	assert.Nil(t, err, "should not error on compact format")
+	assert.Equal(t, `{"a":[1,2,3],"b":{"c":3.141}}`, out)
+
+	var buf bytes.Buffer
+	_, err = json.FormatTo(&buf, WriteOptions{Pretty: true, Indent: "\t", SingleLineArray: true})
+	assert.Nil(t, err, "should not error on format to writer")
+	assert.Equal(t, "{\n\t\"a\": [1, 2, 3],\n\t\"b\": {\n\t\t\"c\": 3.14159265\n\t}\n}", buf.String())
+
+	_, err = json.Format(WriteOptions{Pretty: true, Indent: " \t"})
+	assert.Equal(t, ErrBadIndent, err)
+	_, err = json.Format(WriteOptions{Pretty: true, Indent: "--"})
+	assert.Equal(t, ErrBadIndent, err)
+	_, err = json.Format(WriteOptions{MaxDecimalPlaces: -1})
+	assert.Equal(t, ErrBadDecimalPlaces, err)
+}
+
+func TestNanAndInf(t *testing.T) {
+	json, err := NewParsedStringJson(`{"a":NaN}`)
+	assert.True(t, errors.Is(err, ErrJsonParse), "should error on NaN by default")
+	json.Free()
+
+	json, err = NewParsedStringJsonWithOptions(`{"a":NaN,"b":Infinity,"c":-Inf}`, ParseOptions{NanAndInf: true})
+	assert.Nil(t, err, "should not error on parsing NaN and Inf")
+	defer json.Free()
+
+	assert.Equal(t, "", json.String(), "should not return partial output")
+	assert.Equal(t, 0, len(json.Bytes()))
+	_, err = json.Format(WriteOptions{})
+	assert.Equal(t, ErrNanOrInf, err)
+	assert.True(t, errors.Is(err, ErrSerialize), "should be a serialization error")
+
+	out, err := json.Format(WriteOptions{NanAndInf: true})
+	assert.Nil(t, err, "should not error on writing NaN and Inf")
+	assert.Equal(t, `{"a":NaN,"b":Infinity,"c":-Infinity}`, out)
+
+	ct := json.GetContainer()
+	assert.Nil(t, ct.GetMemberOrNil("a").SetValue(math.Inf(-1)))
+	out, err = json.Format(WriteOptions{Pretty: true, NanAndInf: true, SingleLineArray: true})
+	assert.Nil(t, err, "should not error on pretty writing NaN and Inf")
+	assert.Equal(t, "{\n    \"a\": -Infinity,\n    \"b\": Infinity,\n    \"c\": -Infinity\n}", out)
+
+	var buf bytes.Buffer
+	_, err = ct.GetMemberOrNil("b").WriteTo(&buf)
+	assert.Equal(t, ErrNanOrInf, err)
+	assert.Equal(t, "", ct.GetMemberOrNil("b").String())
+
+	// nothing is written, even past the first chunk
+	for _, input := range []string{`{"a":1,"b":NaN}`, `[` + strings.Repeat(`"text",`, 10000) + `-NaN]`} {
+		for _, opts := range []ParseOptions{{NanAndInf: true}, {NanAndInf: true, LosslessNumbers: true}} {
+			assert.Nil(t, json.ParseStringWithOptions(input, opts))
+			buf.Reset()
+			n, err := json.WriteTo(&buf)
+			assert.Equal(t, ErrNanOrInf, err)
+			assert.Equal(t, int64(0), n)
+			assert.Equal(t, 0, buf.Len())
+		}
+	}
+}